@@ -0,0 +1,121 @@
+package invoke
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CrashError is returned by providers wrapped with CrashArtifacts when a
+// command was killed by a signal. Bundle is the local directory holding the
+// collected diagnostics.
+type CrashError struct {
+	Err    error
+	Bundle string
+}
+
+func (e *CrashError) Error() string {
+	return fmt.Sprintf("%v (crash artifacts in %s)", e.Err, e.Bundle)
+}
+
+func (e *CrashError) Unwrap() error { return e.Err }
+
+// CrashArtifacts returns a Provider that, whenever a command run through p
+// crashes, collects diagnostics from the target through p (kernel log tail,
+// core pattern, recent journal lines) into a new directory under dir. A crash
+// is a death by one of signals, or by any signal that produced a core. When no
+// signals are given, SIGSEGV, SIGABRT, SIGBUS, SIGILL, SIGFPE and SIGKILL (as
+// sent by the OOM killer) are used. Callers whose own timeouts kill commands,
+// such as exec.CommandContext, can pass a set without SIGKILL. Crash detection
+// is only available on Unix hosts.
+func CrashArtifacts(p Provider, dir string, signals ...os.Signal) Provider {
+	if len(signals) == 0 {
+		signals = defaultCrashSignals
+	}
+
+	return &crashProvider{provider: p, dir: dir, signals: signals}
+}
+
+type crashProvider struct {
+	provider Provider
+	dir      string
+	signals  []os.Signal
+}
+
+var crashCollectors = []struct {
+	file string
+	args []string
+}{
+	{file: "dmesg.txt", args: []string{"sh", "-c", "dmesg | tail -n 200"}},
+	{file: "core_pattern.txt", args: []string{"cat", "/proc/sys/kernel/core_pattern"}},
+	{file: "journal.txt", args: []string{"journalctl", "--no-pager", "-n", "200"}},
+}
+
+func (p *crashProvider) Run(c *exec.Cmd) error {
+	err := p.provider.Run(c)
+
+	if c.ProcessState == nil || !crashed(c.ProcessState, p.signals) {
+		return err //nolint:wrapcheck
+	}
+
+	bundle, collectErr := p.collect(c)
+	if collectErr != nil {
+		return errors.Join(err, collectErr)
+	}
+
+	return &CrashError{Err: err, Bundle: bundle}
+}
+
+func (p *crashProvider) collect(c *exec.Cmd) (string, error) {
+	name := filepath.Base(c.Path)
+	if len(c.Args) > 0 {
+		name = filepath.Base(c.Args[0])
+	}
+
+	if err := os.MkdirAll(p.dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating crash bundle: %w", err)
+	}
+
+	// MkdirTemp gives concurrent crashes of the same tool their own bundles.
+	bundle, err := os.MkdirTemp(p.dir, time.Now().UTC().Format("20060102T150405.000Z")+"-"+name+"-*")
+	if err != nil {
+		return "", fmt.Errorf("creating crash bundle: %w", err)
+	}
+
+	summary := fmt.Sprintf("command: %s\nstate: %s\n", strings.Join(c.Args, " "), c.ProcessState)
+	if err := os.WriteFile(filepath.Join(bundle, "command.txt"), []byte(summary), 0o600); err != nil {
+		return "", fmt.Errorf("writing crash bundle: %w", err)
+	}
+
+	// Collectors are best effort: a missing tool or permission error is
+	// recorded in its output file rather than failing the bundle.
+	for _, collector := range crashCollectors {
+		if err := p.runCollector(filepath.Join(bundle, collector.file), collector.args); err != nil {
+			return "", err
+		}
+	}
+
+	return bundle, nil
+}
+
+func (p *crashProvider) runCollector(path string, args []string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing crash bundle: %w", err)
+	}
+	defer f.Close()
+
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec
+	cmd.Stdout = f
+	cmd.Stderr = f
+
+	if err := p.provider.Run(cmd); err != nil {
+		fmt.Fprintf(f, "\n%s: %v\n", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
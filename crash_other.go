@@ -0,0 +1,11 @@
+//go:build !unix
+
+package invoke
+
+import "os"
+
+var defaultCrashSignals []os.Signal
+
+// crashed always reports false: processes are not killed by signals on
+// non-Unix hosts.
+func crashed(*os.ProcessState, []os.Signal) bool { return false }
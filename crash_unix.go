@@ -0,0 +1,33 @@
+//go:build unix
+
+package invoke
+
+import (
+	"os"
+	"syscall"
+)
+
+var defaultCrashSignals = []os.Signal{
+	syscall.SIGSEGV, syscall.SIGABRT, syscall.SIGBUS, syscall.SIGILL, syscall.SIGFPE, syscall.SIGKILL,
+}
+
+// crashed reports whether state describes a process killed by one of signals
+// or by a signal that dumped core.
+func crashed(state *os.ProcessState, signals []os.Signal) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return false
+	}
+
+	if status.CoreDump() {
+		return true
+	}
+
+	for _, sig := range signals {
+		if sig == status.Signal() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+//go:build unix
+
+package invoke
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCrashArtifactsCollectsOnCoreSignal(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	err := CrashArtifacts(runProvider{}, dir).Run(exec.Command("sh", "-c", "kill -SEGV $$"))
+
+	var crash *CrashError
+	if !errors.As(err, &crash) {
+		t.Fatalf("err = %v, want *CrashError", err)
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Errorf("CrashError does not unwrap to the run error: %v", err)
+	}
+
+	if filepath.Dir(crash.Bundle) != dir {
+		t.Errorf("bundle %q is not under %q", crash.Bundle, dir)
+	}
+
+	for _, name := range []string{"command.txt", "dmesg.txt", "core_pattern.txt", "journal.txt"} {
+		if _, err := os.Stat(filepath.Join(crash.Bundle, name)); err != nil {
+			t.Errorf("bundle is missing %s: %v", name, err)
+		}
+	}
+}
+
+func TestCrashArtifactsCollectsOnOOMKill(t *testing.T) {
+	t.Parallel()
+
+	// The OOM killer ends a process with SIGKILL and no core.
+	err := CrashArtifacts(runProvider{}, t.TempDir()).Run(exec.Command("sh", "-c", "kill -KILL $$"))
+
+	var crash *CrashError
+	if !errors.As(err, &crash) {
+		t.Fatalf("err = %v, want *CrashError", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(crash.Bundle, "dmesg.txt")); err != nil {
+		t.Errorf("bundle is missing dmesg.txt: %v", err)
+	}
+}
+
+func TestCrashArtifactsIgnoresNonCrashes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p := CrashArtifacts(runProvider{}, dir)
+
+	if err := p.Run(exec.Command("sh", "-c", "exit 3")); err == nil || errors.As(err, new(*CrashError)) {
+		t.Errorf("exit 3: err = %v, want a plain exit error", err)
+	}
+
+	if err := p.Run(exec.Command("sh", "-c", "kill -TERM $$")); err == nil || errors.As(err, new(*CrashError)) {
+		t.Errorf("SIGTERM: err = %v, want a plain exit error", err)
+	}
+
+	// A caller whose timeouts send SIGKILL leaves it out of the signal set.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	noKill := CrashArtifacts(runProvider{}, dir, syscall.SIGSEGV, syscall.SIGABRT)
+
+	if err := noKill.Run(exec.CommandContext(ctx, "sleep", "5")); err == nil || errors.As(err, new(*CrashError)) {
+		t.Errorf("context timeout: err = %v, want a plain exit error", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("bundles were created for non-crashes: %v", entries)
+	}
+}
+
+func TestCrashArtifactsConcurrentCrashes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p := CrashArtifacts(runProvider{}, dir)
+
+	const n = 4
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		bundles = map[string]bool{}
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var crash *CrashError
+			if err := p.Run(exec.Command("sh", "-c", "kill -ABRT $$")); !errors.As(err, &crash) {
+				t.Errorf("err = %v, want *CrashError", err)
+
+				return
+			}
+
+			mu.Lock()
+			bundles[crash.Bundle] = true
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if len(bundles) != n {
+		t.Errorf("got %d distinct bundles for %d crashes", len(bundles), n)
+	}
+}
@@ -0,0 +1,287 @@
+package invoke
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EnvFromFile parses a dotenv file into KEY=VALUE pairs suitable for
+// exec.Cmd.Env. See EnvFromReader for the accepted syntax.
+func EnvFromFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening env file: %w", err)
+	}
+	defer f.Close()
+
+	env, err := EnvFromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return env, nil
+}
+
+// EnvFromReader parses dotenv syntax into KEY=VALUE pairs suitable for
+// exec.Cmd.Env. Lines may be prefixed with "export"; '#' starts a comment.
+// Single-quoted values are literal, double-quoted values accept \n, \r, \t,
+// \", \\ and \$ escapes, and both may span lines. Nothing is expanded or
+// executed. When a key repeats, the last value wins.
+func EnvFromReader(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading env: %w", err)
+	}
+
+	p := &dotenvParser{src: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1}
+
+	var (
+		env   []string
+		index = map[string]int{}
+	)
+
+	for {
+		key, value, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return env, nil
+		}
+
+		if i, seen := index[key]; seen {
+			env[i] = key + "=" + value
+
+			continue
+		}
+
+		index[key] = len(env)
+		env = append(env, key+"="+value)
+	}
+}
+
+type dotenvParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *dotenvParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("dotenv line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// next returns the next assignment, or ok=false at end of input.
+func (p *dotenvParser) next() (key, value string, ok bool, err error) {
+	for p.pos < len(p.src) {
+		p.skipBlanks()
+
+		switch {
+		case p.pos >= len(p.src):
+			return "", "", false, nil
+		case p.src[p.pos] == '\n':
+			p.pos++
+			p.line++
+
+			continue
+		case p.src[p.pos] == '#':
+			p.skipLine()
+
+			continue
+		}
+
+		if strings.HasPrefix(p.src[p.pos:], "export ") || strings.HasPrefix(p.src[p.pos:], "export\t") {
+			p.pos += len("export")
+			p.skipBlanks()
+		}
+
+		if key, err = p.key(); err != nil {
+			return "", "", false, err
+		}
+
+		p.skipBlanks()
+
+		if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+			return "", "", false, p.errorf("expected '=' after %s", key)
+		}
+
+		p.pos++
+		p.skipBlanks()
+
+		if value, err = p.value(); err != nil {
+			return "", "", false, err
+		}
+
+		return key, value, true, nil
+	}
+
+	return "", "", false, nil
+}
+
+func (p *dotenvParser) key() (string, error) {
+	start := p.pos
+
+	for p.pos < len(p.src) && isEnvKeyByte(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return "", p.errorf("invalid variable name")
+	}
+
+	return p.src[start:p.pos], nil
+}
+
+func (p *dotenvParser) value() (string, error) {
+	if p.pos >= len(p.src) {
+		return "", nil
+	}
+
+	var (
+		value string
+		err   error
+	)
+
+	switch p.src[p.pos] {
+	case '\'':
+		value, err = p.singleQuoted()
+	case '"':
+		value, err = p.doubleQuoted()
+	default:
+		return p.unquoted(), nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	// Only whitespace or a comment may follow a closing quote.
+	p.skipBlanks()
+
+	if p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '#' {
+		return "", p.errorf("unexpected characters after quoted value")
+	}
+
+	p.skipLine()
+
+	return value, nil
+}
+
+func (p *dotenvParser) unquoted() string {
+	start := p.pos
+
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		// A '#' preceded by whitespace starts a comment, including when the
+		// whitespace came between '=' and the value.
+		if p.src[p.pos] == '#' && p.pos > 0 && isBlank(p.src[p.pos-1]) {
+			break
+		}
+
+		p.pos++
+	}
+
+	value := strings.TrimRight(p.src[start:p.pos], " \t")
+	p.skipLine()
+
+	return value
+}
+
+func (p *dotenvParser) singleQuoted() (string, error) {
+	line := p.line
+	p.pos++
+	start := p.pos
+
+	for p.pos < len(p.src) && p.src[p.pos] != '\'' {
+		if p.src[p.pos] == '\n' {
+			p.line++
+		}
+
+		p.pos++
+	}
+
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("dotenv line %d: unterminated single-quoted value", line)
+	}
+
+	value := p.src[start:p.pos]
+	p.pos++
+
+	return value, nil
+}
+
+func (p *dotenvParser) doubleQuoted() (string, error) {
+	line := p.line
+	p.pos++
+
+	var b strings.Builder
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+
+		switch {
+		case c == '"':
+			p.pos++
+
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+
+			switch e := p.src[p.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(e)
+			default:
+				if e == '\n' {
+					p.line++
+				}
+
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+
+			b.WriteByte(c)
+		}
+
+		p.pos++
+	}
+
+	return "", fmt.Errorf("dotenv line %d: unterminated double-quoted value", line)
+}
+
+func (p *dotenvParser) skipBlanks() {
+	for p.pos < len(p.src) && isBlank(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *dotenvParser) skipLine() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isEnvKeyByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9', c == '.':
+		return !first
+	}
+
+	return false
+}
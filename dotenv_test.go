@@ -0,0 +1,110 @@
+package invoke
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvFromReader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"simple", "A=1\nB=2\n", []string{"A=1", "B=2"}},
+		{"no trailing newline", "A=1", []string{"A=1"}},
+		{"crlf", "A=1\r\nB=2\r\n", []string{"A=1", "B=2"}},
+		{"comments and blank lines", "# header\n\n  # indented\nA=1\n", []string{"A=1"}},
+		{"export prefix", "export A=1\nexport\tB=2\n", []string{"A=1", "B=2"}},
+		{"spaces around equals", "A = hello world  \n", []string{"A=hello world"}},
+		{"inline comment", "A=1 # note\n", []string{"A=1"}},
+		{"hash without whitespace is literal", "A=x#y\nB=#z\n", []string{"A=x#y", "B=#z"}},
+		{"empty value", "A=\nB=\n", []string{"A=", "B="}},
+		{"empty value with comment", "FOO= # comment\nBAR=\t# comment\n", []string{"FOO=", "BAR="}},
+		{"single quotes are literal", `A='$HOME \n "x"'`, []string{`A=$HOME \n "x"`}},
+		{"double quote escapes", `A="a\nb\t\"q\" \\ \$HOME \x"`, []string{"A=a\nb\t\"q\" \\ $HOME \\x"}},
+		{"no expansion", "A=$HOME\nB=${A}\nC=$(id)\n", []string{"A=$HOME", "B=${A}", "C=$(id)"}},
+		{"quoted comment", `A="x # y" # z`, []string{"A=x # y"}},
+		{"multiline single quoted", "A='one\ntwo'\nB=3\n", []string{"A=one\ntwo", "B=3"}},
+		{"multiline double quoted", "A=\"one\ntwo\"\nB=3\n", []string{"A=one\ntwo", "B=3"}},
+		{"last value wins in place", "A=1\nB=2\nA=3\n", []string{"A=3", "B=2"}},
+		{"dotted key", "a.b_C1=x\n", []string{"a.b_C1=x"}},
+		{"empty input", "", nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := EnvFromReader(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvFromReaderErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"missing equals", "A=1\nB\n", "dotenv line 2: expected '=' after B"},
+		{"invalid name", "A=1\n\n1B=2\n", "dotenv line 3: invalid variable name"},
+		{"unterminated single quote", "A=1\nB='x\ny\n", "dotenv line 2: unterminated single-quoted value"},
+		{"unterminated double quote", "A=\"x\n", "dotenv line 1: unterminated double-quoted value"},
+		{"text after quote", "A=1\nB=\"x\" y\n", "dotenv line 2: unexpected characters after quoted value"},
+		{"line numbers after multiline value", "A='x\ny'\nB\n", "dotenv line 3: expected '=' after B"},
+		{"line numbers after escaped newline", "A=\"x\\\ny\"\nB\n", "dotenv line 3: expected '=' after B"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := EnvFromReader(strings.NewReader(tt.in))
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvFromFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := EnvFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"A=1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := EnvFromFile(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want not-exist", err)
+	}
+}
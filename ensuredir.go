@@ -0,0 +1,34 @@
+package invoke
+
+import "os/exec"
+
+// WithEnsureDir returns a Provider that creates each command's Dir on the
+// target, like mkdir -p, before running the command there through p. This
+// gives one behaviour for a missing working directory across providers instead
+// of each failing in its own way. The command is started from a POSIX sh on
+// the target, so this is not supported on Windows targets.
+func WithEnsureDir(p Provider) Provider {
+	return &ensureDirProvider{provider: p}
+}
+
+type ensureDirProvider struct {
+	provider Provider
+}
+
+// Run moves Dir into the command line for the duration of the run only; the
+// original Dir is restored before returning.
+func (p *ensureDirProvider) Run(c *exec.Cmd) error {
+	if c.Dir == "" {
+		return p.provider.Run(c) //nolint:wrapcheck
+	}
+
+	dir := c.Dir
+
+	defer func() { c.Dir = dir }()
+
+	c.Dir = ""
+
+	prefix := []string{"sh", "-c", `mkdir -p -- "$0" && cd "$0" && exec "$@"`, dir}
+
+	return (&prefixProvider{provider: p.provider, prefix: prefix}).Run(c)
+}
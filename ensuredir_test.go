@@ -0,0 +1,77 @@
+package invoke
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWithEnsureDirPrefix(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordingProvider{}
+
+	c := exec.Command("make", "install")
+	c.Dir = "/srv/build"
+
+	if err := WithEnsureDir(rec).Run(c); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sh", "-c", `mkdir -p -- "$0" && cd "$0" && exec "$@"`, "/srv/build", "make", "install"}
+	if !reflect.DeepEqual(rec.args[0], want) {
+		t.Errorf("ran %q, want %q", rec.args[0], want)
+	}
+
+	if rec.dirs[0] != "" {
+		t.Errorf("Dir %q was passed on to the provider", rec.dirs[0])
+	}
+
+	if c.Dir != "/srv/build" {
+		t.Errorf("Dir after Run = %q, want /srv/build", c.Dir)
+	}
+
+	if want := []string{"make", "install"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("Args after Run = %q, want %q", c.Args, want)
+	}
+
+	if err := WithEnsureDir(rec).Run(exec.Command("ls")); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"ls"}; !reflect.DeepEqual(rec.args[1], want) {
+		t.Errorf("without Dir ran %q, want %q", rec.args[1], want)
+	}
+}
+
+func TestWithEnsureDirCreatesDir(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX sh")
+	}
+
+	dir := filepath.Join(t.TempDir(), "a", "b")
+
+	var out strings.Builder
+
+	c := exec.Command("pwd")
+	c.Dir = dir
+	c.Stdout = &out
+
+	if err := WithEnsureDir(runProvider{}).Run(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("Dir was not created: %v", err)
+	}
+
+	if got := strings.TrimSpace(out.String()); got != dir {
+		t.Errorf("command ran in %q, want %q", got, dir)
+	}
+}
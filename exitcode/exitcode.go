@@ -0,0 +1,191 @@
+// Package exitcode maps exit codes of well-known tools to semantic categories
+// so callers can decide whether a failure is worth retrying.
+package exitcode
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type Category int
+
+const (
+	Unknown Category = iota
+	Success
+	PartialSuccess
+	Retryable
+	Fatal
+)
+
+func (c Category) String() string {
+	switch c {
+	case Success:
+		return "success"
+	case PartialSuccess:
+		return "partial success"
+	case Retryable:
+		return "retryable"
+	case Fatal:
+		return "fatal"
+	case Unknown:
+	}
+
+	return "unknown"
+}
+
+type Entry struct {
+	Category Category
+	Reason   string
+}
+
+type Registry struct {
+	mu       sync.RWMutex
+	codes    map[string]map[int]Entry
+	fallback map[string]Category
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		codes:    map[string]map[int]Entry{},
+		fallback: map[string]Category{},
+	}
+}
+
+// Register classifies code for tool. Tool names are matched on their base
+// name, case-insensitively and without a .exe suffix.
+func (r *Registry) Register(tool string, code int, category Category, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tool = toolName(tool)
+
+	if r.codes[tool] == nil {
+		r.codes[tool] = map[int]Entry{}
+	}
+
+	r.codes[tool][code] = Entry{Category: category, Reason: reason}
+}
+
+// SetFallback sets the category used for non-zero codes of tool that have not
+// been registered explicitly.
+func (r *Registry) SetFallback(tool string, category Category) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fallback[toolName(tool)] = category
+}
+
+// Lookup returns the entry for code. Unregistered zero codes are Success and
+// other unregistered codes use the tool's fallback, or Unknown.
+func (r *Registry) Lookup(tool string, code int) Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool = toolName(tool)
+
+	if e, ok := r.codes[tool][code]; ok {
+		return e
+	}
+
+	if code == 0 {
+		return Entry{Category: Success}
+	}
+
+	return Entry{Category: r.fallback[tool]}
+}
+
+func (r *Registry) Classify(tool string, code int) Category {
+	return r.Lookup(tool, code).Category
+}
+
+// ClassifyError classifies the error returned from running c. A nil error is
+// Success; errors other than *exec.ExitError are Unknown.
+func (r *Registry) ClassifyError(c *exec.Cmd, err error) Category {
+	if err == nil {
+		return Success
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return Unknown
+	}
+
+	tool := c.Path
+	if len(c.Args) > 0 {
+		tool = c.Args[0]
+	}
+
+	return r.Classify(tool, exitErr.ExitCode())
+}
+
+func toolName(tool string) string {
+	tool = strings.ToLower(filepath.Base(strings.ReplaceAll(tool, `\`, "/")))
+
+	return strings.TrimSuffix(tool, ".exe")
+}
+
+// Default is pre-populated with well-known tools.
+var Default = defaultRegistry()
+
+func Register(tool string, code int, category Category, reason string) {
+	Default.Register(tool, code, category, reason)
+}
+
+func Lookup(tool string, code int) Entry { return Default.Lookup(tool, code) }
+
+func Classify(tool string, code int) Category { return Default.Classify(tool, code) }
+
+func ClassifyError(c *exec.Cmd, err error) Category { return Default.ClassifyError(c, err) }
+
+func defaultRegistry() *Registry {
+	r := NewRegistry()
+
+	r.SetFallback("rsync", Fatal)
+	r.Register("rsync", 5, Retryable, "error starting client-server protocol")
+	r.Register("rsync", 10, Retryable, "error in socket I/O")
+	r.Register("rsync", 12, Retryable, "error in rsync protocol data stream")
+	r.Register("rsync", 23, PartialSuccess, "partial transfer due to error")
+	r.Register("rsync", 24, PartialSuccess, "partial transfer due to vanished source files")
+	r.Register("rsync", 30, Retryable, "timeout in data send/receive")
+	r.Register("rsync", 35, Retryable, "timeout waiting for daemon connection")
+
+	r.SetFallback("curl", Fatal)
+	r.Register("curl", 5, Retryable, "could not resolve proxy")
+	r.Register("curl", 6, Retryable, "could not resolve host")
+	r.Register("curl", 7, Retryable, "failed to connect to host")
+	r.Register("curl", 28, Retryable, "operation timed out")
+	r.Register("curl", 35, Retryable, "TLS handshake failed")
+	r.Register("curl", 52, Retryable, "empty reply from server")
+	r.Register("curl", 55, Retryable, "failed sending network data")
+	r.Register("curl", 56, Retryable, "failure receiving network data")
+
+	r.SetFallback("wget", Fatal)
+	r.Register("wget", 4, Retryable, "network failure")
+
+	for _, apt := range []string{"apt", "apt-get"} {
+		r.SetFallback(apt, Fatal)
+		r.Register(apt, 100, Fatal, "package operation failed")
+	}
+
+	r.SetFallback("systemctl", Fatal)
+	r.Register("systemctl", 3, Fatal, "unit is not active")
+	r.Register("systemctl", 4, Fatal, "no such unit")
+
+	r.SetFallback("ssh", Fatal)
+	r.Register("ssh", 255, Retryable, "ssh connection error")
+
+	// robocopy exit codes are a bit field; anything from 8 up includes failures.
+	r.SetFallback("robocopy", Fatal)
+	r.Register("robocopy", 1, Success, "files copied")
+	r.Register("robocopy", 2, Success, "extra files detected")
+	r.Register("robocopy", 3, Success, "files copied, extra files detected")
+
+	for code := 4; code < 8; code++ {
+		r.Register("robocopy", code, PartialSuccess, "mismatched files or directories detected")
+	}
+
+	return r
+}
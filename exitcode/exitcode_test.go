@@ -0,0 +1,111 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		tool string
+		code int
+		want Category
+	}{
+		{"explicit retryable", "rsync", 30, Retryable},
+		{"explicit partial success", "rsync", 24, PartialSuccess},
+		{"fallback", "rsync", 1, Fatal},
+		{"zero is success", "rsync", 0, Success},
+		{"unregistered tool zero is success", "true", 0, Success},
+		{"unregistered tool", "grep", 1, Unknown},
+		{"apt-get shares apt codes", "apt-get", 100, Fatal},
+		{"robocopy bit field success", "robocopy", 3, Success},
+		{"robocopy mismatch", "robocopy", 5, PartialSuccess},
+		{"robocopy failure", "robocopy", 8, Fatal},
+
+		// Tool names are matched on base name, case-insensitively, without .exe.
+		{"unix path", "/usr/bin/rsync", 23, PartialSuccess},
+		{"windows path", `C:\bin\ROBOCOPY.EXE`, 1, Success},
+		{"mixed case", "SSH", 255, Retryable},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Classify(tt.tool, tt.code); got != tt.want {
+				t.Errorf("Classify(%q, %d) = %v, want %v", tt.tool, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	r.Register(`D:\Tools\Sync.exe`, 7, Retryable, "lock held")
+	r.SetFallback("sync", Fatal)
+
+	if e := r.Lookup("sync", 7); e.Category != Retryable || e.Reason != "lock held" {
+		t.Errorf("Lookup(sync, 7) = %+v, want Retryable with reason", e)
+	}
+
+	if got := r.Classify("/opt/bin/sync", 2); got != Fatal {
+		t.Errorf("fallback = %v, want Fatal", got)
+	}
+
+	r.Register("sync", 0, PartialSuccess, "nothing to do")
+
+	if got := r.Classify("sync", 0); got != PartialSuccess {
+		t.Errorf("registered zero = %v, want PartialSuccess", got)
+	}
+
+	if got := Classify("sync", 7); got != Unknown {
+		t.Errorf("registering on a new registry changed Default: %v", got)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX sh")
+	}
+
+	c := exec.Command("sh", "-c", "exit 23")
+	runErr := c.Run()
+
+	r := NewRegistry()
+	r.Register("sh", 23, PartialSuccess, "test")
+
+	tests := []struct {
+		name string
+		err  error
+		want Category
+	}{
+		{"nil", nil, Success},
+		{"not an exit error", errors.New("connection refused"), Unknown},
+		{"exit error", runErr, PartialSuccess},
+		{"wrapped exit error", fmt.Errorf("deploy: %w", runErr), PartialSuccess},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := r.ClassifyError(c, tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
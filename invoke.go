@@ -19,5 +19,5 @@ func NewWithProvider(p Provider) (*Invoker, error) {
 }
 
 func (i *Invoker) Run(c *exec.Cmd) error {
-	return nil
+	return i.provider.Run(c) //nolint:wrapcheck
 }
@@ -0,0 +1,28 @@
+package invoke
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestInvokerRunDelegatesToProvider(t *testing.T) {
+	t.Parallel()
+
+	wantErr := errors.New("boom")
+	rec := &recordingProvider{err: wantErr}
+
+	i, err := NewWithProvider(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := exec.Command("true")
+	if err := i.Run(c); !errors.Is(err, wantErr) {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+
+	if len(rec.cmds) != 1 || rec.cmds[0] != c {
+		t.Errorf("provider received %v, want the command passed to Run", rec.cmds)
+	}
+}
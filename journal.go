@@ -0,0 +1,246 @@
+package invoke
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	JournalStart  = "start"
+	JournalFinish = "finish"
+)
+
+type JournalEntry struct {
+	ID       string    `json:"id"`
+	Event    string    `json:"event"`
+	Time     time.Time `json:"time"`
+	Args     []string  `json:"args,omitempty"`
+	Dir      string    `json:"dir,omitempty"`
+	ExitCode int       `json:"exitCode,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Journal is an append-only, fsynced record of command start and finish
+// markers. After a controller crash, commands with a start marker but no
+// finish marker may have partially executed on the target.
+type Journal struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenJournal opens or creates the journal at path for appending. A torn final
+// line left by a crash mid-write is truncated first, so new entries start on
+// a fresh line.
+func OpenJournal(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	if err := trimTornTail(f); err != nil {
+		f.Close()
+
+		return nil, err
+	}
+
+	return &Journal{file: f}, nil
+}
+
+// trimTornTail truncates f after its last newline.
+func trimTornTail(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("checking journal: %w", err)
+	}
+
+	const chunk = 4096
+
+	buf := make([]byte, chunk)
+	end := info.Size()
+
+	for off := end; off > 0; {
+		n := int64(chunk)
+		if off < n {
+			n = off
+		}
+
+		off -= n
+
+		if _, err := f.ReadAt(buf[:n], off); err != nil {
+			return fmt.Errorf("checking journal: %w", err)
+		}
+
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = off + int64(i) + 1
+
+			break
+		}
+
+		end = off
+	}
+
+	if end == info.Size() {
+		return nil
+	}
+
+	if err := f.Truncate(end); err != nil {
+		return fmt.Errorf("truncating torn journal entry: %w", err)
+	}
+
+	return f.Sync() //nolint:wrapcheck
+}
+
+func (j *Journal) Close() error {
+	return j.file.Close() //nolint:wrapcheck
+}
+
+// Wrap returns a Provider that journals every command run through p. A
+// command is not started unless its start marker has been written.
+func (j *Journal) Wrap(p Provider) Provider {
+	return &journalProvider{provider: p, journal: j}
+}
+
+func (j *Journal) record(e JournalEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding journal entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("syncing journal: %w", err)
+	}
+
+	return nil
+}
+
+type journalProvider struct {
+	provider Provider
+	journal  *Journal
+}
+
+func (p *journalProvider) Run(c *exec.Cmd) error {
+	id, err := journalID()
+	if err != nil {
+		return err
+	}
+
+	err = p.journal.record(JournalEntry{
+		ID:    id,
+		Event: JournalStart,
+		Time:  time.Now().UTC(),
+		Args:  c.Args,
+		Dir:   c.Dir,
+	})
+	if err != nil {
+		return err
+	}
+
+	runErr := p.provider.Run(c)
+
+	finish := JournalEntry{ID: id, Event: JournalFinish, Time: time.Now().UTC()}
+
+	if c.ProcessState != nil {
+		finish.ExitCode = c.ProcessState.ExitCode()
+	}
+
+	if runErr != nil {
+		finish.Error = runErr.Error()
+	}
+
+	if err := p.journal.record(finish); err != nil {
+		return errors.Join(runErr, err)
+	}
+
+	return runErr
+}
+
+// ReadJournal returns every entry in the journal at path, in order. A torn
+// final line, as left by a crash mid-write, is ignored.
+func ReadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		entries []JournalEntry
+		torn    error
+	)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		if torn != nil {
+			return nil, torn
+		}
+
+		var e JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			torn = fmt.Errorf("decoding journal entry %d: %w", len(entries)+1, err)
+
+			continue
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// PendingCommands returns the start markers in the journal at path that have
+// no matching finish marker.
+func PendingCommands(path string) ([]JournalEntry, error) {
+	entries, err := ReadJournal(path)
+	if err != nil {
+		return nil, err
+	}
+
+	finished := map[string]bool{}
+
+	for _, e := range entries {
+		if e.Event == JournalFinish {
+			finished[e.ID] = true
+		}
+	}
+
+	var pending []JournalEntry
+
+	for _, e := range entries {
+		if e.Event == JournalStart && !finished[e.ID] {
+			pending = append(pending, e)
+		}
+	}
+
+	return pending, nil
+}
+
+func journalID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating journal id: %w", err)
+	}
+
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,199 @@
+package invoke
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openTestJournal(t *testing.T, path string) *Journal {
+	t.Helper()
+
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { j.Close() })
+
+	return j
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j := openTestJournal(t, path)
+
+	failure := errors.New("boom")
+	p := j.Wrap(&recordingProvider{})
+	failing := j.Wrap(&recordingProvider{err: failure})
+
+	ok := exec.Command("ls", "-l")
+	ok.Dir = "/srv"
+
+	if err := p.Run(ok); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := failing.Run(exec.Command("deploy")); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+
+	entries, err := ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4: %+v", len(entries), entries)
+	}
+
+	start, finish := entries[0], entries[1]
+	if start.Event != JournalStart || finish.Event != JournalFinish || start.ID != finish.ID || start.ID == "" {
+		t.Errorf("first command markers do not pair up: %+v %+v", start, finish)
+	}
+
+	if !reflect.DeepEqual(start.Args, []string{"ls", "-l"}) || start.Dir != "/srv" {
+		t.Errorf("start marker = %+v", start)
+	}
+
+	if start.Time.IsZero() || finish.Time.Before(start.Time) {
+		t.Errorf("bad timestamps: %v then %v", start.Time, finish.Time)
+	}
+
+	if entries[2].ID == start.ID {
+		t.Error("commands share a journal ID")
+	}
+
+	if entries[3].Error != "boom" {
+		t.Errorf("failure not recorded: %+v", entries[3])
+	}
+
+	pending, err := PendingCommands(path)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("pending = %+v, %v; want none", pending, err)
+	}
+}
+
+func TestJournalRecordsExitCode(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("false"); err != nil {
+		t.Skip("false not available")
+	}
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j := openTestJournal(t, path)
+
+	if err := j.Wrap(runProvider{}).Run(exec.Command("false")); err == nil {
+		t.Fatal("false succeeded")
+	}
+
+	entries, err := ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if finish := entries[len(entries)-1]; finish.ExitCode != 1 {
+		t.Errorf("ExitCode = %d, want 1", finish.ExitCode)
+	}
+}
+
+func TestJournalPendingCommands(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "journal")
+	j := openTestJournal(t, path)
+
+	// A start marker without a finish, as left by a crash during Run.
+	if err := j.record(JournalEntry{ID: "crashed", Event: JournalStart, Args: []string{"migrate"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.Wrap(&recordingProvider{}).Run(exec.Command("ls")); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := PendingCommands(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 1 || pending[0].ID != "crashed" || !reflect.DeepEqual(pending[0].Args, []string{"migrate"}) {
+		t.Errorf("pending = %+v, want the crashed migrate", pending)
+	}
+}
+
+func TestJournalReopenAfterTornWrite(t *testing.T) {
+	t.Parallel()
+
+	for name, tail := range map[string]string{
+		"torn after complete entry": `{"id":"crashed","event":"start","args":["migrate"]}` + "\n" + `{"id":"torn","ev`,
+		"torn only entry":           `{"id":"torn","ev`,
+		"complete entries only":     `{"id":"crashed","event":"start","args":["migrate"]}` + "\n",
+	} {
+		name, tail := name, tail
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "journal")
+			if err := os.WriteFile(path, []byte(tail), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			j := openTestJournal(t, path)
+
+			start := exec.Command("restart")
+			if err := j.record(JournalEntry{ID: "new", Event: JournalStart, Args: start.Args}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := ReadJournal(path); err != nil {
+				t.Fatalf("journal unreadable after reopen: %v", err)
+			}
+
+			pending, err := PendingCommands(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for _, e := range pending {
+				ids = append(ids, e.ID)
+			}
+
+			want := []string{"crashed", "new"}
+			if name == "torn only entry" {
+				want = []string{"new"}
+			}
+
+			if !reflect.DeepEqual(ids, want) {
+				t.Errorf("pending IDs = %q, want %q", ids, want)
+			}
+		})
+	}
+}
+
+func TestReadJournalIgnoresOnlyTornTail(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "journal")
+	content := `{"id":"a","event":"start"}` + "\n" + `{"id":"b","ev` + "\n" + `{"id":"a","event":"finish"}` + "\n"
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadJournal(path); err == nil {
+		t.Error("corrupt entry in the middle of the journal was ignored")
+	}
+}
+
+type runProvider struct{}
+
+func (runProvider) Run(c *exec.Cmd) error { return c.Run() }
@@ -0,0 +1,49 @@
+package invoke
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ResourceLimits constrains commands run through WithResourceLimits. Zero
+// values leave the corresponding resource unconstrained.
+type ResourceLimits struct {
+	// CPUQuota is the share of one CPU, in percent, e.g. 50 or 200.
+	CPUQuota int
+	// MemoryMax is the memory limit in bytes.
+	MemoryMax int64
+	// Nice is the scheduling niceness adjustment.
+	Nice int
+}
+
+// WithResourceLimits returns a Provider that runs every command through p with
+// the given limits. CPU and memory limits are applied by placing the command
+// in a transient cgroup with systemd-run --scope, which requires systemd and
+// sufficient privileges on the target; niceness is applied with nice.
+func WithResourceLimits(p Provider, limits ResourceLimits) Provider {
+	var prefix []string
+
+	if limits.CPUQuota > 0 || limits.MemoryMax > 0 {
+		prefix = append(prefix, "systemd-run", "--scope", "--quiet", "--collect")
+
+		if limits.CPUQuota > 0 {
+			prefix = append(prefix, fmt.Sprintf("--property=CPUQuota=%d%%", limits.CPUQuota))
+		}
+
+		if limits.MemoryMax > 0 {
+			prefix = append(prefix, "--property=MemoryMax="+strconv.FormatInt(limits.MemoryMax, 10))
+		}
+
+		prefix = append(prefix, "--")
+	}
+
+	if limits.Nice != 0 {
+		prefix = append(prefix, "nice", "-n", strconv.Itoa(limits.Nice), "--")
+	}
+
+	if len(prefix) == 0 {
+		return p
+	}
+
+	return &prefixProvider{provider: p, prefix: prefix}
+}
@@ -0,0 +1,73 @@
+package invoke
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestWithResourceLimitsPrefix(t *testing.T) {
+	t.Parallel()
+
+	scope := []string{"systemd-run", "--scope", "--quiet", "--collect"}
+
+	tests := []struct {
+		name   string
+		limits ResourceLimits
+		want   []string
+	}{
+		{
+			name:   "cpu only",
+			limits: ResourceLimits{CPUQuota: 50},
+			want:   concat(scope, "--property=CPUQuota=50%", "--", "gzip", "f"),
+		},
+		{
+			name:   "memory only",
+			limits: ResourceLimits{MemoryMax: 1 << 30},
+			want:   concat(scope, "--property=MemoryMax=1073741824", "--", "gzip", "f"),
+		},
+		{
+			name:   "nice only",
+			limits: ResourceLimits{Nice: 10},
+			want:   []string{"nice", "-n", "10", "--", "gzip", "f"},
+		},
+		{
+			name:   "combined",
+			limits: ResourceLimits{CPUQuota: 200, MemoryMax: 512, Nice: -5},
+			want: concat(scope, "--property=CPUQuota=200%", "--property=MemoryMax=512", "--",
+				"nice", "-n", "-5", "--", "gzip", "f"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &recordingProvider{}
+
+			if err := WithResourceLimits(rec, tt.limits).Run(exec.Command("gzip", "f")); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(rec.args[0], tt.want) {
+				t.Errorf("ran %q, want %q", rec.args[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestWithResourceLimitsZeroValue(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordingProvider{}
+
+	if p := WithResourceLimits(rec, ResourceLimits{}); p != Provider(rec) {
+		t.Errorf("zero limits returned %T, want the provider unchanged", p)
+	}
+}
+
+func concat(prefix []string, rest ...string) []string {
+	return append(append([]string(nil), prefix...), rest...)
+}
@@ -0,0 +1,9 @@
+package invoke
+
+// HostNamespaces returns a Provider that runs every command through p inside
+// the mount, UTS, network and IPC namespaces of PID 1. Wrapping a provider that
+// executes inside a privileged container lets commands act on the underlying
+// host.
+func HostNamespaces(p Provider) Provider {
+	return &prefixProvider{provider: p, prefix: []string{"nsenter", "-t", "1", "-m", "-u", "-n", "-i", "--"}}
+}
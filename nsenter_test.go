@@ -0,0 +1,37 @@
+package invoke
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+type recordingProvider struct {
+	cmds []*exec.Cmd
+	args [][]string
+	dirs []string
+	err  error
+}
+
+func (p *recordingProvider) Run(c *exec.Cmd) error {
+	p.cmds = append(p.cmds, c)
+	p.args = append(p.args, append([]string(nil), c.Args...))
+	p.dirs = append(p.dirs, c.Dir)
+
+	return p.err
+}
+
+func TestHostNamespacesPrefix(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordingProvider{}
+
+	if err := HostNamespaces(rec).Run(exec.Command("ls", "-l")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"nsenter", "-t", "1", "-m", "-u", "-n", "-i", "--", "ls", "-l"}
+	if !reflect.DeepEqual(rec.args[0], want) {
+		t.Errorf("ran %q, want %q", rec.args[0], want)
+	}
+}
@@ -0,0 +1,253 @@
+// Package parse turns common command output shapes into rows keyed by column
+// or field name.
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrNoHeader = errors.New("no header line")
+
+// MaxLineSize is the longest line KeyValues accepts.
+const MaxLineSize = 1 << 20
+
+type Row map[string]string
+
+// Int parses the value of key as a base-10 integer.
+func (r Row) Int(key string) (int64, error) {
+	v, ok := r[key]
+	if !ok {
+		return 0, fmt.Errorf("field %q not present", key)
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("field %q: %w", key, err)
+	}
+
+	return n, nil
+}
+
+// Table parses whitespace-aligned output with a header line, such as df, ps or
+// lsblk. Trailing header words that no data row has a field under are merged
+// into one multi-word column (e.g. df's "Mounted on"), while a column that is
+// only filled on some rows (lsblk's MOUNTPOINTS) is kept. Fields beyond the
+// column count are joined into the last column, so it keeps embedded spaces (a
+// ps COMMAND, or a mount point such as "/media/My Disk").
+func Table(data []byte) ([]Row, error) {
+	lines := nonEmptyLines(data)
+	if len(lines) == 0 {
+		return nil, ErrNoHeader
+	}
+
+	header := strings.Fields(lines[0])
+
+	if width := columnCount(lines[0], lines[1:]); width < len(header) {
+		header = append(header[:width-1:width-1], strings.Join(header[width-1:], " "))
+	}
+
+	records := make([][]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		records = append(records, splitFields(line, len(header)))
+	}
+
+	rows := make([]Row, 0, len(records))
+
+	for _, fields := range records {
+		row := make(Row, len(header))
+		for i, name := range header {
+			if i < len(fields) {
+				row[name] = fields[i]
+			} else {
+				row[name] = ""
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// KeyValues parses "key<sep>value" lines, such as systemctl show ("=") or
+// lscpu (":"). Blank lines separate records, so output for several units
+// yields several rows. Lines without sep are ignored. Lines longer than
+// MaxLineSize are an error.
+func KeyValues(data []byte, sep string) ([]Row, error) {
+	var (
+		rows    []Row
+		current Row
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, MaxLineSize)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.TrimSpace(line) == "" {
+			if current != nil {
+				rows = append(rows, current)
+				current = nil
+			}
+
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, sep)
+		if !ok {
+			continue
+		}
+
+		if current == nil {
+			current = Row{}
+		}
+
+		current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading key/value output: %w", err)
+	}
+
+	if current != nil {
+		rows = append(rows, current)
+	}
+
+	return rows, nil
+}
+
+// CSV parses comma-separated output whose first record is a header.
+func CSV(data []byte) ([]Row, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing csv: %w", err)
+	}
+
+	if len(records) == 0 {
+		return nil, ErrNoHeader
+	}
+
+	header := records[0]
+	rows := make([]Row, 0, len(records)-1)
+
+	for _, record := range records[1:] {
+		row := make(Row, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			} else {
+				row[name] = ""
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func nonEmptyLines(data []byte) []string {
+	var lines []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// columnCount returns the number of columns in a table with the given header
+// line. Starting from the right, a header word is its own column if some line
+// has a field in that position that overlaps the word; otherwise it is merged
+// into the column before it. It returns the header length when there are no
+// lines.
+func columnCount(header string, lines []string) int {
+	words := fieldSpans(header)
+	if len(lines) == 0 {
+		return len(words)
+	}
+
+	rows := make([][][2]int, 0, len(lines))
+	for _, line := range lines {
+		rows = append(rows, fieldSpans(line))
+	}
+
+	width := len(words)
+
+	for ; width > 1; width-- {
+		word := words[width-1]
+
+		for _, fields := range rows {
+			if len(fields) >= width {
+				if f := fields[width-1]; f[0] < word[1] && word[0] < f[1] {
+					return width
+				}
+			}
+		}
+	}
+
+	return width
+}
+
+// fieldSpans returns the [start, end) rune offsets of each whitespace-separated
+// field in s. Runes rather than bytes keep multibyte text, such as lsblk's tree
+// drawing, aligned with the header.
+func fieldSpans(s string) [][2]int {
+	var spans [][2]int
+
+	start, col := -1, 0
+
+	for _, r := range s + " " {
+		blank := r == ' ' || r == '\t'
+
+		switch {
+		case blank && start >= 0:
+			spans = append(spans, [2]int{start, col})
+			start = -1
+		case !blank && start < 0:
+			start = col
+		}
+
+		col++
+	}
+
+	return spans
+}
+
+// splitFields splits s on runs of whitespace into at most n fields, the last
+// of which holds the remainder of the line.
+func splitFields(s string, n int) []string {
+	var fields []string
+
+	s = strings.TrimSpace(s)
+
+	for s != "" {
+		if len(fields) == n-1 {
+			return append(fields, s)
+		}
+
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			return append(fields, s)
+		}
+
+		fields = append(fields, s[:end])
+		s = strings.TrimLeft(s[end:], " \t")
+	}
+
+	return fields
+}
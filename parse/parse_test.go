@@ -0,0 +1,170 @@
+package parse
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want []Row
+	}{
+		{
+			name: "df merges multi-word header",
+			in: `Filesystem      Size  Used Avail Use% Mounted on
+/dev/sda1        50G   20G   30G  40% /
+tmpfs           3.9G     0  3.9G   0% /dev/shm
+`,
+			want: []Row{
+				{"Filesystem": "/dev/sda1", "Size": "50G", "Used": "20G", "Avail": "30G", "Use%": "40%", "Mounted on": "/"},
+				{"Filesystem": "tmpfs", "Size": "3.9G", "Used": "0", "Avail": "3.9G", "Use%": "0%", "Mounted on": "/dev/shm"},
+			},
+		},
+		{
+			name: "df mount point with spaces",
+			in: `Filesystem      Size  Used Avail Use% Mounted on
+/dev/sda1        50G   20G   30G  40% /
+/dev/sdb1       100G   10G   90G  10% /media/My Disk
+tmpfs           3.9G     0  3.9G   0% /dev/shm
+`,
+			want: []Row{
+				{"Filesystem": "/dev/sda1", "Size": "50G", "Used": "20G", "Avail": "30G", "Use%": "40%", "Mounted on": "/"},
+				{"Filesystem": "/dev/sdb1", "Size": "100G", "Used": "10G", "Avail": "90G", "Use%": "10%", "Mounted on": "/media/My Disk"},
+				{"Filesystem": "tmpfs", "Size": "3.9G", "Used": "0", "Avail": "3.9G", "Use%": "0%", "Mounted on": "/dev/shm"},
+			},
+		},
+		{
+			name: "lsblk keeps a sometimes-empty last column",
+			in: `NAME        MAJ:MIN RM   SIZE RO TYPE MOUNTPOINTS
+nvme0n1     259:0    0 476.9G  0 disk
+├─nvme0n1p1 259:1    0   512M  0 part /boot/efi
+├─nvme0n1p2 259:2    0 475.5G  0 part /
+└─nvme0n1p3 259:3    0   977M  0 part [SWAP]
+sda           8:0    1  28.9G  0 disk
+sdb           8:16   0   1.8T  0 disk
+`,
+			want: []Row{
+				{"NAME": "nvme0n1", "MAJ:MIN": "259:0", "RM": "0", "SIZE": "476.9G", "RO": "0", "TYPE": "disk", "MOUNTPOINTS": ""},
+				{"NAME": "├─nvme0n1p1", "MAJ:MIN": "259:1", "RM": "0", "SIZE": "512M", "RO": "0", "TYPE": "part", "MOUNTPOINTS": "/boot/efi"},
+				{"NAME": "├─nvme0n1p2", "MAJ:MIN": "259:2", "RM": "0", "SIZE": "475.5G", "RO": "0", "TYPE": "part", "MOUNTPOINTS": "/"},
+				{"NAME": "└─nvme0n1p3", "MAJ:MIN": "259:3", "RM": "0", "SIZE": "977M", "RO": "0", "TYPE": "part", "MOUNTPOINTS": "[SWAP]"},
+				{"NAME": "sda", "MAJ:MIN": "8:0", "RM": "1", "SIZE": "28.9G", "RO": "0", "TYPE": "disk", "MOUNTPOINTS": ""},
+				{"NAME": "sdb", "MAJ:MIN": "8:16", "RM": "0", "SIZE": "1.8T", "RO": "0", "TYPE": "disk", "MOUNTPOINTS": ""},
+			},
+		},
+		{
+			name: "last column keeps spaces",
+			in: `  PID USER     COMMAND
+    1 root     /sbin/init splash
+  812 www-data nginx: worker process
+`,
+			want: []Row{
+				{"PID": "1", "USER": "root", "COMMAND": "/sbin/init splash"},
+				{"PID": "812", "USER": "www-data", "COMMAND": "nginx: worker process"},
+			},
+		},
+		{
+			name: "header only",
+			in:   "NAME SIZE\n",
+			want: []Row{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Table([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Table([]byte("\n  \n")); !errors.Is(err, ErrNoHeader) {
+		t.Errorf("empty input: err = %v, want ErrNoHeader", err)
+	}
+}
+
+func TestKeyValues(t *testing.T) {
+	t.Parallel()
+
+	got, err := KeyValues([]byte("Id=a.service\nActiveState=active\nExecStart={ path=/bin/a }\n\n\nId=b.service\r\nnot a pair\nActiveState = failed\n"), "=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Row{
+		{"Id": "a.service", "ActiveState": "active", "ExecStart": "{ path=/bin/a }"},
+		{"Id": "b.service", "ActiveState": "failed"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	colon, err := KeyValues([]byte("Architecture:  x86_64\nModel name:    Example CPU @ 2.00GHz\n"), ":")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []Row{{"Architecture": "x86_64", "Model name": "Example CPU @ 2.00GHz"}}; !reflect.DeepEqual(colon, want) {
+		t.Errorf("got %v, want %v", colon, want)
+	}
+}
+
+func TestKeyValuesLongLine(t *testing.T) {
+	t.Parallel()
+
+	in := "A=1\nB=" + strings.Repeat("x", 2*MaxLineSize) + "\nC=3\n"
+
+	if rows, err := KeyValues([]byte(in), "="); err == nil {
+		t.Errorf("got %v, want an error for a line over MaxLineSize", rows)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+
+	got, err := CSV([]byte("name,size,note\nalpha, 10,\"a, b\"\nbeta,20\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Row{
+		{"name": "alpha", "size": "10", "note": "a, b"},
+		{"name": "beta", "size": "20", "note": ""},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if n, err := got[1].Int("size"); err != nil || n != 20 {
+		t.Errorf("Int(size) = %d, %v", n, err)
+	}
+
+	if _, err := got[0].Int("note"); err == nil {
+		t.Error("Int on a non-numeric field succeeded")
+	}
+
+	if _, err := got[0].Int("missing"); err == nil {
+		t.Error("Int on a missing field succeeded")
+	}
+
+	if _, err := CSV(nil); !errors.Is(err, ErrNoHeader) {
+		t.Errorf("empty input: err = %v, want ErrNoHeader", err)
+	}
+}
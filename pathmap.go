@@ -0,0 +1,184 @@
+package invoke
+
+import (
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+)
+
+// PathStyle identifies the path convention used by a target.
+type PathStyle int
+
+const (
+	POSIXPaths PathStyle = iota
+	WindowsPaths
+)
+
+// PathMapper translates host paths into the convention of a target, applying
+// any registered prefix mappings first (e.g. C:\work -> /mnt/c/work for WSL,
+// or a bind-mount source to its location inside a container).
+type PathMapper struct {
+	style    PathStyle
+	prefixes []pathPrefix
+}
+
+type pathPrefix struct {
+	from string
+	to   string
+	fold bool
+}
+
+func NewPathMapper(style PathStyle) *PathMapper {
+	return &PathMapper{style: style}
+}
+
+// Map registers a prefix translation. Prefixes written in Windows style (a drive
+// letter or backslashes) are matched case-insensitively.
+func (m *PathMapper) Map(from, to string) *PathMapper {
+	m.prefixes = append(m.prefixes, pathPrefix{
+		from: normalizePath(from),
+		to:   normalizePath(to),
+		fold: isWindowsPath(from),
+	})
+
+	// Longest prefix wins.
+	sort.SliceStable(m.prefixes, func(i, j int) bool {
+		return len(m.prefixes[i].from) > len(m.prefixes[j].from)
+	})
+
+	return m
+}
+
+func (m *PathMapper) Translate(p string) string {
+	if p == "" {
+		return ""
+	}
+
+	out := normalizePath(p)
+
+	for _, prefix := range m.prefixes {
+		if rest, ok := cutPathPrefix(out, prefix.from, prefix.fold); ok {
+			out = joinPath(prefix.to, rest)
+
+			break
+		}
+	}
+
+	if m.style == WindowsPaths {
+		return ToWindowsPath(out)
+	}
+
+	return ToPOSIXPath(out)
+}
+
+func ToPOSIXPath(p string) string {
+	if p == "" {
+		return ""
+	}
+
+	return normalizePath(p)
+}
+
+func ToWindowsPath(p string) string {
+	if p == "" {
+		return ""
+	}
+
+	return strings.ReplaceAll(normalizePath(p), "/", `\`)
+}
+
+// WithPathMapper returns a Provider that translates each command's Dir with m
+// before running it through p.
+func WithPathMapper(p Provider, m *PathMapper) Provider {
+	return &pathMapperProvider{provider: p, mapper: m}
+}
+
+type pathMapperProvider struct {
+	provider Provider
+	mapper   *PathMapper
+}
+
+// Run translates Dir for the duration of the run only; the original Dir is
+// restored before returning.
+func (p *pathMapperProvider) Run(c *exec.Cmd) error {
+	if c.Dir != "" {
+		dir := c.Dir
+
+		defer func() { c.Dir = dir }()
+
+		c.Dir = p.mapper.Translate(dir)
+	}
+
+	return p.provider.Run(c) //nolint:wrapcheck
+}
+
+// normalizePath converts Windows-style paths to forward slashes and cleans the
+// result, preserving a leading UNC double slash and the separator of a drive
+// root ("C:/" rather than the drive-relative "C:"). Backslashes in POSIX paths
+// are part of the name and are left alone.
+func normalizePath(p string) string {
+	if isWindowsPath(p) {
+		p = strings.ReplaceAll(p, `\`, "/")
+	}
+
+	if strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "///") {
+		return "/" + path.Clean(p[1:])
+	}
+
+	cleaned := path.Clean(p)
+	if len(cleaned) == 2 && cleaned[1] == ':' && len(p) > 2 && p[2] == '/' {
+		return cleaned + "/"
+	}
+
+	return cleaned
+}
+
+// joinPath appends rest to the normalized path base without cleaning, so a
+// drive root or UNC prefix in base is kept.
+func joinPath(base, rest string) string {
+	rest = strings.TrimPrefix(rest, "/")
+
+	switch {
+	case rest == "":
+		return base
+	case strings.HasSuffix(base, "/"):
+		return base + rest
+	default:
+		return base + "/" + rest
+	}
+}
+
+// isWindowsPath reports whether p is written in Windows style: it has a drive
+// letter, starts with a backslash, or is a relative path using only
+// backslashes.
+func isWindowsPath(p string) bool {
+	if strings.HasPrefix(p, `\`) {
+		return true
+	}
+
+	if strings.Contains(p, `\`) && !strings.Contains(p, "/") {
+		return true
+	}
+
+	return len(p) >= 2 && p[1] == ':' &&
+		(('a' <= p[0] && p[0] <= 'z') || ('A' <= p[0] && p[0] <= 'Z'))
+}
+
+func cutPathPrefix(p, prefix string, fold bool) (string, bool) {
+	if len(p) < len(prefix) {
+		return "", false
+	}
+
+	head, rest := p[:len(prefix)], p[len(prefix):]
+
+	if fold && !strings.EqualFold(head, prefix) || !fold && head != prefix {
+		return "", false
+	}
+
+	if rest == "" || rest[0] == '/' || strings.HasSuffix(prefix, "/") {
+		return rest, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,82 @@
+package invoke
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestPathMapperTranslate(t *testing.T) {
+	t.Parallel()
+
+	posix := NewPathMapper(POSIXPaths).Map(`C:\work`, "/mnt/c/work").Map("/src", "/app").Map("/src/vendor", "/vendor")
+	windows := NewPathMapper(WindowsPaths).Map("/mnt/c", "C:/")
+	driveRoot := NewPathMapper(WindowsPaths).Map("/mnt/c", `C:\`).Map("/share", `\\srv\share`)
+
+	tests := []struct {
+		name   string
+		mapper *PathMapper
+		in     string
+		want   string
+	}{
+		{"windows prefix", posix, `C:\work\a\b.txt`, "/mnt/c/work/a/b.txt"},
+		{"windows prefix is case-insensitive", posix, `c:\WORK\a`, "/mnt/c/work/a"},
+		{"prefix must end on a separator", posix, `C:\workspace`, "C:/workspace"},
+		{"posix prefix", posix, "/src/x", "/app/x"},
+		{"posix prefix exact", posix, "/src", "/app"},
+		{"posix prefix is case-sensitive", posix, "/SRC/x", "/SRC/x"},
+		{"longest prefix wins", posix, "/src/vendor/lib", "/vendor/lib"},
+		{"posix backslash is part of the name", posix, `/srv/a\b`, `/srv/a\b`},
+		{"unmatched is cleaned", posix, "/a/./b/../c", "/a/c"},
+		{"empty", posix, "", ""},
+		{"to windows", windows, "/mnt/c/work/a", `C:\work\a`},
+		{"unc to windows", windows, "//srv/share/f", `\\srv\share\f`},
+		{"exact match onto drive root", driveRoot, "/mnt/c", `C:\`},
+		{"below drive root", driveRoot, "/mnt/c/Users", `C:\Users`},
+		{"onto unc prefix", driveRoot, "/share/f", `\\srv\share\f`},
+		{"drive root is kept", windows, `D:\`, `D:\`},
+		{"drive-relative is kept", windows, "D:", "D:"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.mapper.Translate(tt.in); got != tt.want {
+				t.Errorf("Translate(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPathMapperRewritesDir(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordingProvider{}
+	p := WithPathMapper(rec, NewPathMapper(POSIXPaths).Map(`C:\work`, "/mnt/c/work"))
+
+	c := exec.Command("ls")
+	c.Dir = `C:\work\project`
+
+	if err := p.Run(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.dirs[0] != "/mnt/c/work/project" {
+		t.Errorf("provider got Dir %q, want /mnt/c/work/project", rec.dirs[0])
+	}
+
+	if c.Dir != `C:\work\project` {
+		t.Errorf("Dir after Run = %q, want the caller's Dir", c.Dir)
+	}
+
+	empty := exec.Command("ls")
+	if err := p.Run(empty); err != nil {
+		t.Fatal(err)
+	}
+
+	if empty.Dir != "" {
+		t.Errorf("empty Dir rewritten to %q", empty.Dir)
+	}
+}
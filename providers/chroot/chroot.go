@@ -0,0 +1,57 @@
+package chroot
+
+import (
+	"os/exec"
+
+	"github.com/ruffel/invoke"
+	"github.com/ruffel/invoke/providers/local"
+)
+
+// Chroot runs commands inside a chroot at Root. Command paths and Dir are
+// interpreted inside the chroot.
+type Chroot struct {
+	Root     string
+	provider invoke.Provider
+}
+
+// Provider returns a Chroot that enters Root on the local host.
+func Provider(root string) *Chroot { return Wrap(local.Provider(), root) }
+
+// Wrap returns a Chroot that runs the chroot command through p.
+func Wrap(p invoke.Provider, root string) *Chroot { return &Chroot{Root: root, provider: p} }
+
+// Run rewrites c for the duration of the run only. Its original Path, Args, Dir
+// and Err are restored before returning, so callers and outer decorators see
+// the command they passed.
+func (p *Chroot) Run(c *exec.Cmd) error {
+	path, origArgs, dir, lookErr := c.Path, c.Args, c.Dir, c.Err
+
+	defer func() { c.Path, c.Args, c.Dir, c.Err = path, origArgs, dir, lookErr }()
+
+	args := c.Args
+	if len(args) == 0 {
+		args = []string{c.Path}
+	}
+
+	chrootArgs := []string{p.Root}
+
+	// chroot always starts in the new root's "/", so change into Dir from
+	// inside the chroot rather than on the host.
+	if c.Dir != "" {
+		chrootArgs = append(chrootArgs, "/bin/sh", "-c", `cd "$0" && exec "$@"`, c.Dir)
+		c.Dir = ""
+	}
+
+	wrapped := exec.Command("chroot", append(chrootArgs, args...)...) //nolint:gosec
+
+	c.Path = wrapped.Path
+	c.Args = wrapped.Args
+	c.Err = wrapped.Err
+
+	inner := p.provider
+	if inner == nil {
+		inner = local.Provider()
+	}
+
+	return inner.Run(c) //nolint:wrapcheck
+}
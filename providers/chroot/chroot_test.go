@@ -0,0 +1,79 @@
+package chroot
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+type recordingProvider struct {
+	args [][]string
+	dirs []string
+	err  error
+}
+
+func (p *recordingProvider) Run(c *exec.Cmd) error {
+	p.args = append(p.args, append([]string(nil), c.Args...))
+	p.dirs = append(p.dirs, c.Dir)
+
+	return p.err
+}
+
+func TestChrootArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		dir  string
+		want []string
+	}{
+		{"no dir", "", []string{"chroot", "/srv/root", "ls", "-l"}},
+		{"dir", "/var/lib", []string{"chroot", "/srv/root", "/bin/sh", "-c", `cd "$0" && exec "$@"`, "/var/lib", "ls", "-l"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &recordingProvider{}
+			c := exec.Command("ls", "-l")
+			c.Dir = tt.dir
+
+			if err := Wrap(rec, "/srv/root").Run(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(rec.args[0], tt.want) {
+				t.Errorf("ran %q, want %q", rec.args[0], tt.want)
+			}
+
+			if rec.dirs[0] != "" {
+				t.Errorf("Dir %q was applied on the host", rec.dirs[0])
+			}
+		})
+	}
+}
+
+func TestChrootRestoresCommand(t *testing.T) {
+	t.Parallel()
+
+	wantErr := errors.New("boom")
+	c := exec.Command("rsync", "-a", "src/", "dst/")
+	c.Dir = "/work"
+	path := c.Path
+
+	if err := Wrap(&recordingProvider{err: wantErr}, "/srv/root").Run(c); !errors.Is(err, wantErr) {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+
+	if want := []string{"rsync", "-a", "src/", "dst/"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("Args after Run = %q, want %q", c.Args, want)
+	}
+
+	if c.Path != path || c.Dir != "/work" {
+		t.Errorf("Path, Dir after Run = %q, %q, want %q, /work", c.Path, c.Dir, path)
+	}
+}
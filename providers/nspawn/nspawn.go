@@ -0,0 +1,113 @@
+package nspawn
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ruffel/invoke"
+	"github.com/ruffel/invoke/providers/local"
+)
+
+// Nspawn runs commands inside a systemd-nspawn machine via systemd-run. The
+// command's exit status is propagated, and Dir and Env are applied inside the
+// machine.
+type Nspawn struct {
+	Machine  string
+	provider invoke.Provider
+}
+
+// Provider returns an Nspawn that runs systemd-run on the local host.
+func Provider(machine string) *Nspawn { return Wrap(local.Provider(), machine) }
+
+// Wrap returns an Nspawn that runs the systemd-run command through p.
+func Wrap(p invoke.Provider, machine string) *Nspawn { return &Nspawn{Machine: machine, provider: p} }
+
+// Run rewrites c for the duration of the run only. Its original Path, Args, Dir
+// and Err are restored before returning, so callers and outer decorators see
+// the command they passed.
+func (p *Nspawn) Run(c *exec.Cmd) error {
+	path, origArgs, dir, lookErr := c.Path, c.Args, c.Dir, c.Err
+
+	defer func() { c.Path, c.Args, c.Dir, c.Err = path, origArgs, dir, lookErr }()
+
+	args := c.Args
+	if len(args) == 0 {
+		args = []string{c.Path}
+	}
+
+	runArgs := []string{"-M", p.Machine, "--pipe", "--wait", "--quiet", "--collect"}
+
+	if c.Dir != "" {
+		runArgs = append(runArgs, "--working-directory="+c.Dir)
+		c.Dir = ""
+	}
+
+	// Pass only names: systemd-run copies each value from its own environment,
+	// which is c.Env, so values never appear on the command line.
+	for _, kv := range c.Env {
+		if name, _, _ := strings.Cut(kv, "="); name != "" {
+			runArgs = append(runArgs, "--setenv="+name)
+		}
+	}
+
+	wrapped := exec.Command("systemd-run", append(append(runArgs, "--"), args...)...) //nolint:gosec
+
+	c.Path = wrapped.Path
+	c.Args = wrapped.Args
+	c.Err = wrapped.Err
+
+	inner := p.provider
+	if inner == nil {
+		inner = local.Provider()
+	}
+
+	return inner.Run(c) //nolint:wrapcheck
+}
+
+// CopyTo copies a host path into the machine.
+func (p *Nspawn) CopyTo(src, dst string) error {
+	return machinectl("copy-to", p.Machine, src, dst)
+}
+
+// CopyFrom copies a path from the machine to the host.
+func (p *Nspawn) CopyFrom(src, dst string) error {
+	return machinectl("copy-from", p.Machine, src, dst)
+}
+
+// Machines lists the names of running machines known to machined.
+func Machines() ([]string, error) {
+	var stdout, stderr bytes.Buffer
+
+	c := exec.Command("machinectl", "list", "--no-legend", "--no-pager")
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("machinectl list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var names []string
+
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			names = append(names, fields[0])
+		}
+	}
+
+	return names, nil
+}
+
+func machinectl(args ...string) error {
+	var stderr bytes.Buffer
+
+	c := exec.Command("machinectl", args...)
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("machinectl %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package nspawn
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+type recordingProvider struct {
+	args [][]string
+	dirs []string
+	envs [][]string
+	err  error
+}
+
+func (p *recordingProvider) Run(c *exec.Cmd) error {
+	p.args = append(p.args, append([]string(nil), c.Args...))
+	p.dirs = append(p.dirs, c.Dir)
+	p.envs = append(p.envs, c.Env)
+
+	return p.err
+}
+
+func TestNspawnArgs(t *testing.T) {
+	t.Parallel()
+
+	base := []string{"systemd-run", "-M", "web", "--pipe", "--wait", "--quiet", "--collect"}
+
+	tests := []struct {
+		name string
+		dir  string
+		env  []string
+		want []string
+	}{
+		{
+			name: "plain",
+			want: append(append([]string(nil), base...), "--", "ls", "-l"),
+		},
+		{
+			name: "working directory",
+			dir:  "/srv/app",
+			want: append(append([]string(nil), base...), "--working-directory=/srv/app", "--", "ls", "-l"),
+		},
+		{
+			name: "env names only",
+			env:  []string{"TOKEN=s3cret", "EMPTY=", "=ignored"},
+			want: append(append([]string(nil), base...), "--setenv=TOKEN", "--setenv=EMPTY", "--", "ls", "-l"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &recordingProvider{}
+			c := exec.Command("ls", "-l")
+			c.Dir = tt.dir
+			c.Env = tt.env
+
+			if err := Wrap(rec, "web").Run(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(rec.args[0], tt.want) {
+				t.Errorf("ran %q, want %q", rec.args[0], tt.want)
+			}
+
+			if rec.dirs[0] != "" {
+				t.Errorf("Dir %q was applied on the host", rec.dirs[0])
+			}
+
+			// Values reach systemd-run through its environment, not argv.
+			if !reflect.DeepEqual(rec.envs[0], tt.env) {
+				t.Errorf("Env = %q, want %q", rec.envs[0], tt.env)
+			}
+		})
+	}
+}
+
+func TestNspawnRestoresCommand(t *testing.T) {
+	t.Parallel()
+
+	wantErr := errors.New("boom")
+	c := exec.Command("rsync", "-a", "src/", "dst/")
+	c.Dir = "/work"
+	path := c.Path
+
+	if err := Wrap(&recordingProvider{err: wantErr}, "web").Run(c); !errors.Is(err, wantErr) {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+
+	if want := []string{"rsync", "-a", "src/", "dst/"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("Args after Run = %q, want %q", c.Args, want)
+	}
+
+	if c.Path != path || c.Dir != "/work" {
+		t.Errorf("Path, Dir after Run = %q, %q, want %q, /work", c.Path, c.Dir, path)
+	}
+}
@@ -0,0 +1,392 @@
+package invoke
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var ErrReadOnly = errors.New("command rejected by read-only provider")
+
+// ReadOnly returns a Provider that refuses commands that look like they
+// mutate the target, for building plan or audit modes. The check is a
+// heuristic over the command line: known mutating tools and subcommands are
+// rejected, as are shells and script interpreters whose programs cannot be
+// inspected. Wrappers such as sudo, env and timeout are looked through to the
+// command they run. Extra executable names to reject may be passed in deny.
+func ReadOnly(p Provider, deny ...string) Provider {
+	denied := make(map[string]struct{}, len(deny))
+	for _, name := range deny {
+		denied[normalizeCommandName(name)] = struct{}{}
+	}
+
+	return &readOnlyProvider{provider: p, deny: denied}
+}
+
+type readOnlyProvider struct {
+	provider Provider
+	deny     map[string]struct{}
+}
+
+func (p *readOnlyProvider) Run(c *exec.Cmd) error {
+	args := c.Args
+	if len(args) == 0 {
+		args = []string{c.Path}
+	}
+
+	if reason := p.mutation(args); reason != "" {
+		return fmt.Errorf("%w: %s", ErrReadOnly, reason)
+	}
+
+	return p.provider.Run(c) //nolint:wrapcheck
+}
+
+type wrapperSpec struct {
+	valueFlags    map[string]struct{}
+	shellFlags    map[string]struct{}
+	mutatingFlags map[string]struct{} // flags that make the wrapper itself modify the target
+	// directFlags, when non-nil, lists the flags under which the wrapper runs
+	// its command directly. Without one of them, the remaining arguments are
+	// handed to a shell (su, runuser).
+	directFlags  map[string]struct{}
+	assignments  bool // NAME=VALUE arguments may precede the command
+	positionals  int  // positional arguments that precede the command
+	defaultShell bool // without a command, an interactive shell is started
+}
+
+var (
+	mutatingCommands = stringSet(
+		"rm", "rmdir", "mv", "cp", "dd", "ln", "touch", "mkdir", "truncate", "shred", "tee",
+		"chmod", "chown", "chgrp", "chattr", "setfacl", "install", "rsync", "scp", "tar", "unzip",
+		"kill", "killall", "pkill", "reboot", "shutdown", "halt", "poweroff",
+		"useradd", "userdel", "usermod", "groupadd", "groupdel", "groupmod", "passwd", "chpasswd",
+		"crontab", "mount", "umount", "swapon", "swapoff", "modprobe", "rmmod", "insmod", "sysctl",
+		"iptables", "ip6tables", "nft", "ufw", "firewall-cmd", "update-alternatives",
+		"mkfs", "fdisk", "parted", "wipefs", "lvcreate", "lvremove", "vgcreate", "pvcreate",
+		"del", "erase", "copy", "move", "rd", "md", "icacls", "takeown", "reg", "sc", "net",
+		"sudoedit",
+	)
+	shellCommands = stringSet("sh", "bash", "dash", "zsh", "ksh", "fish", "cmd", "powershell", "pwsh")
+
+	// Interpreters run inline or file scripts that cannot be inspected, just
+	// like shells.
+	interpreterCommands = stringSet("perl", "python", "python2", "python3", "ruby", "node", "nodejs",
+		"php", "lua", "tclsh", "deno", "bun", "osascript", "wscript", "cscript")
+
+	// wrapperCommands run another command. Each spec lists the wrapper's flags
+	// that take a separate value, so parsing can skip to the wrapped command.
+	wrapperCommands = map[string]wrapperSpec{
+		"sudo": {
+			valueFlags: stringSet("-u", "--user", "-g", "--group", "-h", "--host", "-p", "--prompt", "-C",
+				"--close-from", "-r", "--role", "-t", "--type", "-D", "--chdir", "-U", "--other-user",
+				"-T", "--command-timeout", "-c", "--login-class"),
+			shellFlags:    stringSet("-s", "--shell", "-i", "--login"),
+			mutatingFlags: stringSet("-e", "--edit"),
+		},
+		"doas":    {valueFlags: stringSet("-u", "-C"), shellFlags: stringSet("-s")},
+		"env":     {valueFlags: stringSet("-u", "--unset", "-C", "--chdir"), assignments: true},
+		"nohup":   {},
+		"nice":    {valueFlags: stringSet("-n", "--adjustment")},
+		"ionice":  {valueFlags: stringSet("-c", "--class", "-n", "--classdata", "-p", "--pid", "-P", "--pgid", "-u", "--uid")},
+		"timeout": {valueFlags: stringSet("-s", "--signal", "-k", "--kill-after"), positionals: 1},
+		"nsenter": {valueFlags: stringSet("-t", "--target", "-S", "--setuid", "-G", "--setgid"), defaultShell: true},
+		"xargs": {valueFlags: stringSet("-a", "--arg-file", "-d", "--delimiter", "-E", "-I", "-L",
+			"--max-lines", "-n", "--max-args", "-P", "--max-procs", "-s", "--max-chars")},
+		"chroot": {positionals: 1, defaultShell: true},
+		"systemd-run": {
+			valueFlags: stringSet("-u", "--unit", "-p", "--property", "-M", "--machine", "-H", "--host",
+				"-E", "--setenv", "--description", "--slice", "--service-type", "--uid", "--gid", "--nice",
+				"--working-directory", "--on-active", "--on-boot", "--on-startup", "--on-unit-active",
+				"--on-unit-inactive", "--on-calendar", "--path-property", "--socket-property", "--timer-property"),
+			shellFlags: stringSet("-S", "--shell"),
+		},
+		"su": {directFlags: stringSet()},
+		"runuser": {
+			valueFlags: stringSet("-u", "--user", "-g", "--group", "-G", "--supp-group", "-s", "--shell",
+				"-c", "--command", "--session-command", "-w", "--whitelist-environment"),
+			directFlags: stringSet("-u", "--user"),
+		},
+		"setsid": {},
+		"stdbuf": {valueFlags: stringSet("-i", "--input", "-o", "--output", "-e", "--error")},
+		"time": {
+			valueFlags:    stringSet("-f", "--format", "-o", "--output"),
+			mutatingFlags: stringSet("-o", "--output"),
+		},
+	}
+
+	// subcommandValueFlags lists global flags that take a separate value and
+	// may appear before a tool's subcommand.
+	subcommandValueFlags = map[string]map[string]struct{}{
+		"systemctl": stringSet("-H", "--host", "-M", "--machine", "-t", "--type", "-p", "--property",
+			"-s", "--signal", "-n", "--lines", "-o", "--output", "--root", "--state", "--job-mode"),
+		"apt":     stringSet("-o", "--option", "-c", "--config-file", "-t", "--target-release"),
+		"apt-get": stringSet("-o", "--option", "-c", "--config-file", "-t", "--target-release"),
+		"yum":     stringSet("-c", "--config", "-d", "--debuglevel", "-e", "--errorlevel", "--installroot"),
+		"dnf":     stringSet("-c", "--config", "-d", "--debuglevel", "-e", "--errorlevel", "--installroot"),
+		"zypper":  stringSet("-c", "--config", "-R", "--root"),
+		"apk":     stringSet("-X", "--repository", "-p", "--root"),
+		"git":     stringSet("-C", "-c", "--git-dir", "--work-tree", "--namespace"),
+		"docker":  stringSet("-H", "--host", "-c", "--context", "--config", "-l", "--log-level"),
+		"kubectl": stringSet("-n", "--namespace", "--context", "--cluster", "--user", "--kubeconfig",
+			"-s", "--server", "--as"),
+	}
+
+	// nestedSubcommands lists management commands whose own subcommand is the
+	// one checked against mutatingSubcommands (e.g. docker container rm).
+	nestedSubcommands = map[string]map[string]struct{}{
+		"docker": stringSet("container", "image", "volume", "network", "system", "builder", "buildx", "plugin",
+			"secret", "config", "service", "stack", "node", "swarm", "context", "trust", "manifest"),
+	}
+
+	mutatingSubcommands = map[string]map[string]struct{}{
+		"systemctl": stringSet("start", "stop", "restart", "reload", "try-restart", "reload-or-restart", "kill",
+			"enable", "disable", "reenable", "mask", "unmask", "daemon-reload", "isolate", "edit",
+			"set-property", "reboot", "poweroff", "halt", "set-default"),
+		"service": stringSet("start", "stop", "restart", "reload", "force-reload"),
+		"apt":     stringSet("install", "remove", "purge", "upgrade", "full-upgrade", "update", "autoremove"),
+		"apt-get": stringSet("install", "remove", "purge", "upgrade", "dist-upgrade", "update", "autoremove"),
+		"yum":     stringSet("install", "remove", "erase", "update", "upgrade", "downgrade", "autoremove"),
+		"dnf":     stringSet("install", "remove", "erase", "update", "upgrade", "downgrade", "autoremove"),
+		"apk":     stringSet("add", "del", "update", "upgrade"),
+		"zypper":  stringSet("install", "in", "remove", "rm", "update", "up", "dist-upgrade", "dup"),
+		"pip":     stringSet("install", "uninstall"),
+		"pip3":    stringSet("install", "uninstall"),
+		"npm":     stringSet("install", "i", "uninstall", "update", "publish"),
+		"git": stringSet("add", "commit", "push", "pull", "fetch", "reset", "clean", "checkout", "switch",
+			"merge", "rebase", "restore", "rm", "mv", "stash", "tag", "init", "clone"),
+		"docker": stringSet("run", "create", "start", "stop", "restart", "kill", "rm", "rmi", "exec", "pull",
+			"push", "build", "tag", "cp", "commit", "prune", "compose", "up", "down", "remove", "update",
+			"rename", "pause", "unpause", "load", "import", "connect", "disconnect", "install", "enable",
+			"disable", "upgrade", "scale", "deploy", "init", "join", "leave"),
+		"kubectl": stringSet("apply", "create", "delete", "edit", "patch", "replace", "scale", "rollout",
+			"drain", "cordon", "uncordon", "label", "annotate", "exec", "cp", "set"),
+	}
+)
+
+// mutation returns a description of why args look mutating, or "" if they
+// appear to be inspection only.
+func (p *readOnlyProvider) mutation(args []string) string {
+	name := normalizeCommandName(args[0])
+
+	if _, ok := p.deny[name]; ok {
+		return fmt.Sprintf("%s is denied", name)
+	}
+
+	if _, ok := mutatingCommands[name]; ok || strings.HasPrefix(name, "mkfs.") {
+		return fmt.Sprintf("%s modifies the target", name)
+	}
+
+	if _, ok := shellCommands[name]; ok {
+		return fmt.Sprintf("%s scripts cannot be inspected", name)
+	}
+
+	if _, ok := interpreterCommands[name]; ok || strings.HasPrefix(name, "python") {
+		return fmt.Sprintf("%s scripts cannot be inspected", name)
+	}
+
+	if spec, ok := wrapperCommands[name]; ok {
+		return p.wrapperMutation(name, spec, args)
+	}
+
+	switch name {
+	case "sed":
+		if arg := sedInPlace(args); arg != "" {
+			return fmt.Sprintf("%s %s edits files in place", name, arg)
+		}
+	case "find":
+		for _, arg := range args[1:] {
+			switch arg {
+			case "-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprintf", "-fls":
+				return fmt.Sprintf("find %s may modify the target", arg)
+			}
+		}
+	}
+
+	if subcommands, ok := mutatingSubcommands[name]; ok {
+		valueFlags := subcommandValueFlags[name]
+		sub, i := subcommand(args, valueFlags)
+
+		if _, ok := nestedSubcommands[name][sub]; ok {
+			nested, _ := subcommand(args[i:], valueFlags)
+			if _, ok := subcommands[nested]; ok {
+				return fmt.Sprintf("%s %s %s modifies the target", name, sub, nested)
+			}
+
+			return ""
+		}
+
+		if _, ok := subcommands[sub]; ok && sub != "" {
+			return fmt.Sprintf("%s %s modifies the target", name, sub)
+		}
+	}
+
+	return ""
+}
+
+// wrapperMutation skips past the wrapper's own flags and arguments and checks
+// the single command it wraps.
+func (p *readOnlyProvider) wrapperMutation(name string, spec wrapperSpec, args []string) string {
+	shell, direct := false, false
+	i := 1
+
+	for i < len(args) {
+		arg := args[i]
+
+		if arg == "--" {
+			i++
+
+			break
+		}
+
+		if name == "env" && (strings.HasPrefix(arg, "-S") || strings.HasPrefix(arg, "--split-string")) {
+			return "env -S command strings cannot be inspected"
+		}
+
+		if strings.HasPrefix(arg, "-") {
+			flags, takesNext := wrapperFlags(arg, spec)
+
+			for _, flag := range flags {
+				if _, ok := spec.mutatingFlags[flag]; ok {
+					return fmt.Sprintf("%s %s modifies the target", name, flag)
+				}
+
+				_, isShell := spec.shellFlags[flag]
+				_, isDirect := spec.directFlags[flag]
+				shell = shell || isShell
+				direct = direct || isDirect
+			}
+
+			if takesNext {
+				i += 2
+			} else {
+				i++
+			}
+
+			continue
+		}
+
+		if spec.assignments && strings.Contains(arg, "=") {
+			i++
+
+			continue
+		}
+
+		break
+	}
+
+	if spec.directFlags != nil && !direct {
+		return fmt.Sprintf("%s runs commands through a shell", name)
+	}
+
+	i += spec.positionals
+
+	if i >= len(args) {
+		if shell || spec.defaultShell {
+			return fmt.Sprintf("%s without a command starts a shell", name)
+		}
+
+		return ""
+	}
+
+	return p.mutation(args[i:])
+}
+
+// wrapperFlags splits the flag argument arg into the individual flags it sets
+// and reports whether it consumes the following argument as a value. Short
+// flags may be clustered ("-nu root") or carry an attached value ("-uroot").
+func wrapperFlags(arg string, spec wrapperSpec) (flags []string, takesNext bool) {
+	if strings.HasPrefix(arg, "--") {
+		flag, _, hasValue := strings.Cut(arg, "=")
+		_, valued := spec.valueFlags[flag]
+
+		return []string{flag}, valued && !hasValue
+	}
+
+	for j := 1; j < len(arg); j++ {
+		flag := "-" + arg[j:j+1]
+		flags = append(flags, flag)
+
+		if _, ok := spec.valueFlags[flag]; ok {
+			return flags, j == len(arg)-1
+		}
+	}
+
+	return flags, false
+}
+
+// subcommand returns the first non-flag argument and its index, skipping the
+// values of any flags in valueFlags.
+func subcommand(args []string, valueFlags map[string]struct{}) (string, int) {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			if i+1 < len(args) {
+				return args[i+1], i + 1
+			}
+
+			return "", len(args)
+		}
+
+		if !strings.HasPrefix(arg, "-") {
+			return arg, i
+		}
+
+		if _, ok := valueFlags[arg]; ok {
+			i++
+		}
+	}
+
+	return "", len(args)
+}
+
+// sedInPlace returns the argument that makes sed edit files in place, or "".
+// Short flags may be clustered ("-Ei"); the values of -e, -f and -l are
+// skipped so scripts and file names are not mistaken for flags.
+func sedInPlace(args []string) string {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return ""
+		case arg == "--in-place" || strings.HasPrefix(arg, "--in-place="):
+			return arg
+		case arg == "--expression" || arg == "--file" || arg == "--line-length":
+			i++
+		case strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--"):
+		cluster:
+			for j := 1; j < len(arg); j++ {
+				switch arg[j] {
+				case 'i':
+					return arg
+				case 'e', 'f', 'l':
+					if j == len(arg)-1 {
+						i++
+					}
+
+					break cluster
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+func normalizeCommandName(name string) string {
+	name = strings.ToLower(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+
+	return strings.TrimSuffix(name, ".exe")
+}
+
+func stringSet(values ...string) map[string]struct{} {
+	m := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		m[v] = struct{}{}
+	}
+
+	return m
+}
@@ -0,0 +1,151 @@
+package invoke
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args   []string
+		reason string // empty when the command is allowed
+	}{
+		{args: []string{"ls", "-la"}},
+		{args: []string{"cat", "/etc/passwd"}},
+		{args: []string{"git", "status"}},
+		{args: []string{"systemctl", "status", "nginx"}},
+		{args: []string{"/bin/rm", "-rf", "x"}, reason: "rm modifies the target"},
+		{args: []string{`C:\Windows\System32\ICACLS.EXE`, "f"}, reason: "icacls modifies the target"},
+		{args: []string{"mkfs.ext4", "/dev/sdb"}, reason: "mkfs.ext4 modifies the target"},
+		{args: []string{"curl", "https://example.com"}, reason: "curl is denied"},
+
+		// Wrappers are looked through to the single command they run.
+		{args: []string{"sudo", "cat", "/etc/passwd"}},
+		{args: []string{"sudo", "ls", "/srv/backup/tar"}},
+		{args: []string{"sudo", "-u", "rm", "ls"}},
+		{args: []string{"sudo", "-u", "deploy", "systemctl", "restart", "app"}, reason: "systemctl restart modifies the target"},
+		{args: []string{"sudo", "-nu", "root", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"sudo", "--user=root", "--", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"sudo", "-i"}, reason: "sudo without a command starts a shell"},
+		{args: []string{"env", "FOO=1", "true"}},
+		{args: []string{"env", "-i", "PATH=/bin", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"env", "-", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"env", "-S", "rm x"}, reason: "env -S command strings cannot be inspected"},
+		{args: []string{"timeout", "-k", "5", "30", "cat", "f"}},
+		{args: []string{"timeout", "30", "reboot"}, reason: "reboot modifies the target"},
+		{args: []string{"nice", "-n", "10", "tar", "cf", "x.tar", "."}, reason: "tar modifies the target"},
+		{args: []string{"nohup", "cat", "f"}},
+		{args: []string{"nsenter", "-t", "1", "-m"}, reason: "nsenter without a command starts a shell"},
+		{args: []string{"chroot", "/srv/root", "ls"}},
+		{args: []string{"chroot", "/srv/root"}, reason: "chroot without a command starts a shell"},
+		{args: []string{"xargs", "-n", "1", "rm"}, reason: "rm modifies the target"},
+		{args: []string{"systemd-run", "rm", "-rf", "/"}, reason: "rm modifies the target"},
+		{args: []string{"systemd-run", "--scope", "--property=CPUQuota=50%", "-p", "MemoryMax=1G", "--", "cat", "f"}},
+		{args: []string{"systemd-run", "-M", "web", "--pipe", "--wait", "--", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"systemd-run", "-S"}, reason: "systemd-run without a command starts a shell"},
+		{args: []string{"su", "-c", "rm x"}, reason: "su runs commands through a shell"},
+		{args: []string{"su", "deploy"}, reason: "su runs commands through a shell"},
+		{args: []string{"runuser", "-l", "deploy", "-c", "rm x"}, reason: "runuser runs commands through a shell"},
+		{args: []string{"runuser", "-u", "deploy", "--", "cat", "f"}},
+		{args: []string{"runuser", "-u", "deploy", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"setsid", "-f", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"stdbuf", "-oL", "-e", "0", "cat", "f"}},
+		{args: []string{"stdbuf", "-oL", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"time", "rm", "x"}, reason: "rm modifies the target"},
+		{args: []string{"time", "-f", "%e", "ls"}},
+		{args: []string{"time", "-o", "out.txt", "ls"}, reason: "time -o modifies the target"},
+		{args: []string{"sudo", "-e", "/etc/hosts"}, reason: "sudo -e modifies the target"},
+		{args: []string{"sudo", "--edit", "/etc/hosts"}, reason: "sudo --edit modifies the target"},
+		{args: []string{"sudoedit", "/etc/hosts"}, reason: "sudoedit modifies the target"},
+
+		// Only the first non-flag argument is a subcommand.
+		{args: []string{"git", "log", "--grep", "push"}},
+		{args: []string{"git", "-C", "/srv/repo", "log"}},
+		{args: []string{"git", "-C", "/srv/repo", "push"}, reason: "git push modifies the target"},
+		{args: []string{"docker", "ps", "--filter", "name=rm"}},
+		{args: []string{"docker", "--context", "prod", "rm", "c1"}, reason: "docker rm modifies the target"},
+		{args: []string{"docker", "container", "ls", "-a"}},
+		{args: []string{"docker", "container", "rm", "x"}, reason: "docker container rm modifies the target"},
+		{args: []string{"docker", "image", "rm", "alpine"}, reason: "docker image rm modifies the target"},
+		{args: []string{"docker", "volume", "rm", "data"}, reason: "docker volume rm modifies the target"},
+		{args: []string{"docker", "system", "prune", "-f"}, reason: "docker system prune modifies the target"},
+		{args: []string{"docker", "system", "df"}},
+		{args: []string{"kubectl", "-n", "apply", "get", "pods"}},
+		{args: []string{"apt-get", "-y", "install", "curl"}, reason: "apt-get install modifies the target"},
+
+		{args: []string{"sed", "-i", "s/a/b/", "f"}, reason: "sed -i edits files in place"},
+		{args: []string{"sed", "s/a/b/", "f"}},
+		{args: []string{"sed", "-Ei", "s/a/b/", "f"}, reason: "sed -Ei edits files in place"},
+		{args: []string{"sed", "-n", "-i.bak", "s/a/b/p", "f"}, reason: "sed -i.bak edits files in place"},
+		{args: []string{"sed", "--in-place=.bak", "s/a/b/", "f"}, reason: "sed --in-place=.bak edits files in place"},
+		{args: []string{"sed", "-ne", "s/i/x/p", "f"}},
+		{args: []string{"find", "/", "-name", "x", "-delete"}, reason: "find -delete may modify the target"},
+		{args: []string{"find", "/", "-name", "x"}},
+
+		// Shells and interpreters cannot be inspected.
+		{args: []string{"sh", "-c", "ls"}, reason: "sh scripts cannot be inspected"},
+		{args: []string{"perl", "-e", "unlink 'x'"}, reason: "perl scripts cannot be inspected"},
+		{args: []string{"python3", "-c", "import os"}, reason: "python3 scripts cannot be inspected"},
+		{args: []string{"python3.11", "script.py"}, reason: "python3.11 scripts cannot be inspected"},
+		{args: []string{"ruby", "-e", "File.delete('x')"}, reason: "ruby scripts cannot be inspected"},
+		{args: []string{"node", "-e", "require('fs')"}, reason: "node scripts cannot be inspected"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			t.Parallel()
+
+			rec := &recordingProvider{}
+			err := ReadOnly(rec, "curl").Run(exec.Command(tt.args[0], tt.args[1:]...)) //nolint:gosec
+
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("unexpected rejection: %v", err)
+				}
+
+				if len(rec.cmds) != 1 {
+					t.Fatal("allowed command was not run")
+				}
+
+				return
+			}
+
+			if !errors.Is(err, ErrReadOnly) {
+				t.Fatalf("err = %v, want ErrReadOnly", err)
+			}
+
+			if want := ErrReadOnly.Error() + ": " + tt.reason; err.Error() != want {
+				t.Errorf("err = %q, want %q", err, want)
+			}
+
+			if len(rec.cmds) != 0 {
+				t.Error("rejected command was run")
+			}
+		})
+	}
+}
+
+func TestReadOnlyBehindResourceLimits(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordingProvider{}
+	p := WithResourceLimits(ReadOnly(rec), ResourceLimits{CPUQuota: 50, Nice: 10})
+
+	if err := p.Run(exec.Command("rm", "-rf", "/srv")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("rm: err = %v, want ErrReadOnly", err)
+	}
+
+	if err := p.Run(exec.Command("cat", "/etc/hosts")); err != nil {
+		t.Errorf("cat: unexpected error %v", err)
+	}
+
+	if len(rec.cmds) != 1 {
+		t.Errorf("provider ran %d commands, want 1", len(rec.cmds))
+	}
+}
@@ -0,0 +1,107 @@
+// Package rollback runs compensating actions in reverse order when a later
+// step of a multi-step change fails.
+package rollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Func func(ctx context.Context) error
+
+type Plan struct {
+	mu    sync.Mutex
+	steps []step
+}
+
+type step struct {
+	name string
+	undo Func
+}
+
+func New() *Plan {
+	return &Plan{}
+}
+
+// Register records undo as the compensation for a step that has already
+// completed. A nil undo is ignored.
+func (p *Plan) Register(name string, undo Func) {
+	if undo == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.steps = append(p.steps, step{name: name, undo: undo})
+}
+
+// Do runs do and, if it succeeds, registers undo. If do fails, every
+// registered compensation is run and the combined error is returned. A nil
+// undo marks a step that needs no compensation.
+//
+// do often fails because ctx was cancelled or timed out, so compensations run
+// with a context that keeps ctx's values but not its cancellation or deadline.
+func (p *Plan) Do(ctx context.Context, name string, do Func, undo Func) error {
+	if err := do(ctx); err != nil {
+		err = fmt.Errorf("%s: %w", name, err)
+
+		if rbErr := p.Rollback(detach(ctx)); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+
+		return err
+	}
+
+	p.Register(name, undo)
+
+	return nil
+}
+
+// Rollback runs registered compensations with ctx, most recent first. It
+// continues past failures and returns them joined. The plan is empty
+// afterwards. Callers rolling back after a cancellation should pass a context
+// that is still live.
+func (p *Plan) Rollback(ctx context.Context) error {
+	p.mu.Lock()
+	steps := p.steps
+	p.steps = nil
+	p.mu.Unlock()
+
+	var errs []error
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		if err := steps[i].undo(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("rolling back %s: %w", steps[i].name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Commit discards all registered compensations once the change has succeeded.
+func (p *Plan) Commit() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.steps = nil
+}
+
+// detachedContext carries the values of a parent context without its
+// cancellation or deadline.
+type detachedContext struct {
+	parent context.Context //nolint:containedctx
+}
+
+func detach(ctx context.Context) context.Context { return detachedContext{parent: ctx} }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key any) any { return c.parent.Value(key) }
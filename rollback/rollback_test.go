@@ -0,0 +1,123 @@
+package rollback
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDoRollsBackInReverseOrder(t *testing.T) {
+	t.Parallel()
+
+	var undone []string
+
+	undo := func(name string) Func {
+		return func(context.Context) error {
+			undone = append(undone, name)
+
+			return nil
+		}
+	}
+	ok := func(context.Context) error { return nil }
+	failure := errors.New("boom")
+
+	p := New()
+	ctx := context.Background()
+
+	if err := p.Do(ctx, "backup", ok, undo("backup")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Do(ctx, "chmod", ok, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Do(ctx, "upload", ok, undo("upload")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := p.Do(ctx, "restart", func(context.Context) error { return failure }, undo("restart"))
+	if !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+
+	if want := []string{"upload", "backup"}; !reflect.DeepEqual(undone, want) {
+		t.Errorf("undone = %q, want %q", undone, want)
+	}
+
+	if err := p.Rollback(ctx); err != nil || len(undone) != 2 {
+		t.Errorf("second Rollback ran compensations again (err=%v)", err)
+	}
+}
+
+func TestDoRollsBackWithLiveContextAfterCancel(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "deploy-42"))
+
+	var (
+		undoErr error
+		value   any
+	)
+
+	p := New()
+	p.Register("upload", func(ctx context.Context) error {
+		undoErr = ctx.Err()
+		value = ctx.Value(key{})
+
+		return nil
+	})
+
+	err := p.Do(ctx, "restart", func(ctx context.Context) error {
+		cancel()
+
+		return ctx.Err()
+	}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	if undoErr != nil {
+		t.Errorf("compensation ran with a cancelled context: %v", undoErr)
+	}
+
+	if value != "deploy-42" {
+		t.Errorf("compensation context lost values: got %v", value)
+	}
+}
+
+func TestRollbackJoinsErrors(t *testing.T) {
+	t.Parallel()
+
+	first, second := errors.New("first"), errors.New("second")
+
+	ran := 0
+	p := New()
+	p.Register("a", func(context.Context) error { ran++; return first })
+	p.Register("nil", nil)
+	p.Register("b", func(context.Context) error { ran++; return second })
+
+	err := p.Rollback(context.Background())
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Errorf("err = %v, want both compensation errors", err)
+	}
+
+	if ran != 2 {
+		t.Errorf("ran %d compensations, want 2", ran)
+	}
+}
+
+func TestCommitDiscardsCompensations(t *testing.T) {
+	t.Parallel()
+
+	p := New()
+	p.Register("a", func(context.Context) error { t.Error("compensation ran after Commit"); return nil })
+	p.Commit()
+
+	if err := p.Rollback(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,165 @@
+package invoke
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sponge is an io.Writer for command output of unpredictable size. The first
+// limit bytes are kept in memory and anything beyond is spilled to a temporary
+// file, so output is neither truncated nor held entirely in memory. The
+// captured output is readable through ReadAt. Close removes the temporary
+// file, after which Write and ReadAt return os.ErrClosed.
+type Sponge struct {
+	mu     sync.Mutex
+	limit  int
+	mem    []byte
+	file   *os.File
+	size   int64
+	closed bool
+}
+
+func NewSponge(limit int) *Sponge {
+	return &Sponge{limit: limit}
+}
+
+func (s *Sponge) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+
+	n := 0
+
+	if room := s.limit - len(s.mem); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+
+		s.mem = append(s.mem, p[:room]...)
+		n = room
+	}
+
+	if n < len(p) {
+		if s.file == nil {
+			f, err := os.CreateTemp("", "invoke-sponge-*")
+			if err != nil {
+				s.size += int64(n)
+
+				return n, fmt.Errorf("creating spill file: %w", err)
+			}
+
+			s.file = f
+		}
+
+		m, err := s.file.WriteAt(p[n:], s.size+int64(n)-int64(len(s.mem)))
+		n += m
+
+		if err != nil {
+			s.size += int64(n)
+
+			return n, fmt.Errorf("writing spill file: %w", err)
+		}
+	}
+
+	s.size += int64(n)
+
+	return n, nil
+}
+
+func (s *Sponge) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+
+	if off < 0 {
+		return 0, fmt.Errorf("sponge: negative offset %d", off)
+	}
+
+	if off >= s.size {
+		return 0, io.EOF
+	}
+
+	n := 0
+
+	if off < int64(len(s.mem)) {
+		n = copy(p, s.mem[off:])
+	}
+
+	if n < len(p) && s.file != nil {
+		rest := p[n:]
+		if remaining := s.size - off - int64(n); int64(len(rest)) > remaining {
+			rest = rest[:remaining]
+		}
+
+		m, err := s.file.ReadAt(rest, off+int64(n)-int64(len(s.mem)))
+		n += m
+
+		if err != nil && err != io.EOF {
+			return n, fmt.Errorf("reading spill file: %w", err)
+		}
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Reader returns a reader over everything written so far.
+func (s *Sponge) Reader() *io.SectionReader {
+	return io.NewSectionReader(s, 0, s.Size())
+}
+
+func (s *Sponge) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.size
+}
+
+// Spilled reports whether output has exceeded the in-memory limit.
+func (s *Sponge) Spilled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file != nil
+}
+
+func (s *Sponge) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+	s.mem = nil
+
+	if s.file == nil {
+		return nil
+	}
+
+	name := s.file.Name()
+	closeErr := s.file.Close()
+	s.file = nil
+
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("removing spill file: %w", err)
+	}
+
+	if closeErr != nil {
+		return fmt.Errorf("closing spill file: %w", closeErr)
+	}
+
+	return nil
+}
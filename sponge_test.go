@@ -0,0 +1,99 @@
+package invoke
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestSpongeSpillsAcrossWrites(t *testing.T) {
+	t.Parallel()
+
+	for _, limit := range []int{0, 1, 7, 10, 11, 1000} {
+		s := NewSponge(limit)
+
+		var want []byte
+
+		// Uneven writes that straddle the memory limit and each other.
+		for i, chunk := range []string{"abc", "defgh", "", "ijklmnopq", "r", "stuvwxyz0123456789"} {
+			n, err := s.Write([]byte(chunk))
+			if err != nil || n != len(chunk) {
+				t.Fatalf("limit %d write %d: n=%d err=%v", limit, i, n, err)
+			}
+
+			want = append(want, chunk...)
+		}
+
+		if s.Size() != int64(len(want)) {
+			t.Errorf("limit %d: Size = %d, want %d", limit, s.Size(), len(want))
+		}
+
+		if spilled := s.Spilled(); spilled != (limit < len(want)) {
+			t.Errorf("limit %d: Spilled = %v", limit, spilled)
+		}
+
+		got, err := io.ReadAll(s.Reader())
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("limit %d: read %q (err %v), want %q", limit, got, err, want)
+		}
+
+		// Every window, including ones crossing the memory/file boundary.
+		for off := 0; off < len(want); off++ {
+			buf := make([]byte, 5)
+
+			n, err := s.ReadAt(buf, int64(off))
+
+			end := off + 5
+			if end > len(want) {
+				end = len(want)
+			}
+
+			if !bytes.Equal(buf[:n], want[off:end]) {
+				t.Errorf("limit %d ReadAt(%d) = %q, want %q", limit, off, buf[:n], want[off:end])
+			}
+
+			if short := end-off < 5; short != errors.Is(err, io.EOF) || (!short && err != nil) {
+				t.Errorf("limit %d ReadAt(%d) err = %v", limit, off, err)
+			}
+		}
+
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSpongeClose(t *testing.T) {
+	t.Parallel()
+
+	s := NewSponge(4)
+	if _, err := s.Write([]byte("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	spill := s.file.Name()
+	s.mu.Unlock()
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(spill); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("spill file still exists: %v", err)
+	}
+
+	if _, err := s.ReadAt(make([]byte, 10), 0); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("ReadAt after Close: err = %v, want os.ErrClosed", err)
+	}
+
+	if _, err := s.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write after Close: err = %v, want os.ErrClosed", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package invoke
+
+import "os/exec"
+
+// AsUser returns a Provider that runs every command through p as user, using
+// non-interactive sudo.
+func AsUser(p Provider, user string) Provider {
+	return &prefixProvider{provider: p, prefix: []string{"sudo", "-n", "-u", user, "--"}}
+}
+
+// WithSudoEnvironment returns a Provider that runs every command through p as
+// root, using non-interactive sudo.
+func WithSudoEnvironment(p Provider) Provider {
+	return &prefixProvider{provider: p, prefix: []string{"sudo", "-n", "--"}}
+}
+
+type prefixProvider struct {
+	provider Provider
+	prefix   []string
+}
+
+// Run rewrites c for the duration of the run only. Its original Path, Args and
+// Err are restored before returning, so callers and outer decorators (e.g.
+// exitcode.ClassifyError, CrashArtifacts) see the command they passed.
+func (p *prefixProvider) Run(c *exec.Cmd) error {
+	path, args, lookErr := c.Path, c.Args, c.Err
+
+	defer func() { c.Path, c.Args, c.Err = path, args, lookErr }()
+
+	prefixCommand(c, p.prefix...)
+
+	return p.provider.Run(c) //nolint:wrapcheck
+}
+
+// prefixCommand rewrites c in place so that it runs as an argument to prefix,
+// resolving the new executable the same way exec.Command does.
+func prefixCommand(c *exec.Cmd, prefix ...string) {
+	args := c.Args
+	if len(args) == 0 {
+		args = []string{c.Path}
+	}
+
+	wrapped := exec.Command(prefix[0], append(prefix[1:len(prefix):len(prefix)], args...)...) //nolint:gosec
+
+	c.Path = wrapped.Path
+	c.Args = wrapped.Args
+	c.Err = wrapped.Err
+}
@@ -0,0 +1,67 @@
+package invoke
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestPrefixDecorators(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		wrap func(Provider) Provider
+		want []string
+	}{
+		{"as user", func(p Provider) Provider { return AsUser(p, "deploy") }, []string{"sudo", "-n", "-u", "deploy", "--", "ls", "-l"}},
+		{"sudo", WithSudoEnvironment, []string{"sudo", "-n", "--", "ls", "-l"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &recordingProvider{}
+
+			if err := tt.wrap(rec).Run(exec.Command("ls", "-l")); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(rec.args[0], tt.want) {
+				t.Errorf("ran %q, want %q", rec.args[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixProviderRestoresCommand(t *testing.T) {
+	t.Parallel()
+
+	wantErr := errors.New("boom")
+	rec := &recordingProvider{err: wantErr}
+
+	c := exec.Command("rsync", "-a", "src/", "dst/")
+	path := c.Path
+
+	// Nested decorators must each restore what they rewrote.
+	err := AsUser(HostNamespaces(rec), "deploy").Run(c)
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+
+	if got := rec.args[0][0]; got != "nsenter" {
+		t.Errorf("inner provider ran %q, want nsenter", got)
+	}
+
+	if want := []string{"rsync", "-a", "src/", "dst/"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("Args after Run = %q, want %q", c.Args, want)
+	}
+
+	if c.Path != path {
+		t.Errorf("Path after Run = %q, want %q", c.Path, path)
+	}
+}
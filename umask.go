@@ -0,0 +1,16 @@
+package invoke
+
+import (
+	"fmt"
+	"os"
+)
+
+// WithUmask returns a Provider that runs every command through p with the
+// given file-creation mask, so files created by the command get predictable
+// permissions. The command is started from a POSIX sh on the target, so this
+// is not supported on Windows targets.
+func WithUmask(p Provider, mask os.FileMode) Provider {
+	script := fmt.Sprintf(`umask %04o && exec "$@"`, mask&os.ModePerm)
+
+	return &prefixProvider{provider: p, prefix: []string{"sh", "-c", script, "sh"}}
+}
@@ -0,0 +1,48 @@
+package invoke
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestWithUmaskPrefix(t *testing.T) {
+	t.Parallel()
+
+	rec := &recordingProvider{}
+
+	if err := WithUmask(rec, 0o027).Run(exec.Command("touch", "f")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sh", "-c", `umask 0027 && exec "$@"`, "sh", "touch", "f"}
+	if !reflect.DeepEqual(rec.args[0], want) {
+		t.Errorf("ran %q, want %q", rec.args[0], want)
+	}
+}
+
+func TestWithUmaskAppliesToCreatedFiles(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("umask is POSIX only")
+	}
+
+	path := filepath.Join(t.TempDir(), "created")
+
+	if err := WithUmask(runProvider{}, 0o077).Run(exec.Command("touch", path)); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("created file mode = %o, want 600", perm)
+	}
+}